@@ -43,9 +43,11 @@ row0| 0 | 1 | 2 | 3 |
 相邻可见的格子:
 0格子只能看到0,1,4,5
 6格子能看到6,1,2,3,5,7,9,10,11
+相邻半径可以通过WithRadius配置, 半径2时为5x5, 以此类推
 
 AOI事件规则:
 !!!任何事件都不通知事件的trigger!!!
+(SetListener设置的对象监听除外, 行动人和观察者都会收到自己视角的事件)
 
 1. 进入事件: 通知九宫格内所有观察者进入事件
 2. 离开事件: 通知九宫格内所有观察者离开事件
@@ -53,14 +55,40 @@ AOI事件规则:
 a. 通知离开的的九宫格(之前所在的九宫格-新进入的九宫格)离开事件
 b. 通知没变的九宫格(之前所在的九宫格和新进入的九宫格取交集)移动事件
 c. 通知新进入的九宫格(新进入的九宫格-之前所在的九宫格)进入事件
+4. 可见半径: 设置了可见半径(WithViewRadius)的对象, 九宫格只用作粗筛, 再按距离判断是否可见
 */
 const (
 	// GridLength 一边3个格子
 	GridLength = 3
 	// GridNum 九宫格
 	GridNum = GridLength * GridLength
+	// DefaultRadius 默认相邻半径(格子数), 即九宫格
+	DefaultRadius = GridLength / 2
 )
 
+// options 构造选项
+type options struct {
+	radius int  // 相邻半径(格子数)
+	sparse bool // 稀疏模式
+	hex    bool // 六边形格子
+	wrap   bool // 环形地图
+	// 维护可见集合
+	visibility bool
+	// 切换格子的滞后距离
+	hysteresis float64
+}
+
+// Option 构造选项
+type Option func(*options)
+
+// WithRadius 相邻半径(格子数)
+// 默认1即3x3九宫格, 2为5x5, 3为7x7
+func WithRadius(radius int) Option {
+	return func(o *options) {
+		o.radius = radius
+	}
+}
+
 // EventType aoi 事件
 type EventType int
 
@@ -111,6 +139,11 @@ func (o ObjType) IsObserver() bool {
 // ObjID 对象id
 type ObjID = comparable
 
+// Coord 坐标类型, 地图范围, 格子宽高和对象坐标都使用同一种类型
+type Coord interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
 // EventCallback 事件回调, event 事件类型, other 其他对象的id
 // ***注意***
 // 事件不会回调事件触发者
@@ -119,147 +152,372 @@ type ObjID = comparable
 type EventCallback[T ObjID] func(event EventType, other T)
 
 // obj 对象
-type obj struct {
+type obj[T ObjID, P Coord] struct {
+	id T
 	// 所在格子id
 	gridID int
-	// 坐标
-	x, y int
+	// 坐标, 2d时z始终为0
+	x, y, z P
 	// 是否是观察者, 非观察者不接受事件通知
 	ot ObjType
+	// 可见半径, <=0 表示相邻格子内都可见
+	viewRadius float64
+	// 对象自己的事件监听, 见SetListener
+	listener Listener[T, P]
+	// 可见集合, 开启WithVisibility时才有
+	sees, seenBy set[T]
+	// 所在的层和能看到的层, 见WithMask
+	layer, sight uint64
+}
+
+// enterOptions 进入选项
+type enterOptions struct {
+	viewRadius float64
+	// 所在的层和能看到的层
+	layer, sight uint64
+	listener     any // Listener[T, P]
 }
 
+// EnterOption 进入选项
+type EnterOption func(*enterOptions)
+
+// WithViewRadius 对象的可见半径
+// 格子只用来粗筛, 相邻格子内的对象再按欧式距离过滤
+// 可见半径超过相邻格子的范围时, 仍然以相邻格子为准
+func WithViewRadius(radius float64) EnterOption {
+	return func(o *enterOptions) {
+		o.viewRadius = radius
+	}
+}
+
+// Manager aoi接口, 不同的aoi实现(九宫格, 十字链表)遵循相同的事件规则
+type Manager[T ObjID, P Coord] interface {
+	// Enter 进入
+	Enter(id T, posX, posY P, ot ObjType, cb EventCallback[T], opts ...EnterOption) bool
+	// Leave 离开
+	Leave(id T, cb EventCallback[T]) bool
+	// Move 移动
+	Move(id T, toPosX, toPosY P, cb EventCallback[T]) bool
+	// Clear 清空
+	Clear()
+}
+
+var _ Manager[int, int] = (*AOIManager[int, int])(nil)
+
 // AOIManager aoi管理器
-type AOIManager[T ObjID] struct {
-	minX, minY, maxX, maxY int        // 地图范围
-	gridW, gridH           int        // 格子宽高
-	row, col               int        // 总行数 总列数
-	grids                  []*Grid[T] // 所有格子
-	objs                   map[T]*obj // 对象的坐标
+type AOIManager[T ObjID, P Coord] struct {
+	minX, minY, maxX, maxY P                   // 地图范围
+	gridW, gridH           P                   // 格子宽高
+	row, col               int                 // 总行数 总列数
+	minZ, maxZ, gridD      P                   // 3d的高度范围和格子高度
+	layer                  int                 // 总层数, 2d时为1
+	radius                 int                 // 相邻半径(格子数)
+	length                 int                 // 相邻块一边的格子数 2*radius+1
+	hex                    bool                // 六边形格子
+	wrap                   bool                // 环形地图
+	visibility             bool                // 维护可见集合
+	hysteresis             float64             // 切换格子的滞后距离
+	masked                 int                 // 设置了层的对象数
+	visibleFunc            VisibleFunc[T]      // 自定义可见规则
+	grids                  []*Grid[T, P]       // 所有格子
+	sparse                 map[int]*Grid[T, P] // 稀疏模式下已分配的格子, 非稀疏模式为nil
+	objs                   map[T]*obj[T, P]    // 对象的坐标
+	typeCount              map[ObjType]int     // 每种类型的对象数
+	ranged                 int                 // 设置了可见半径的对象数
+	handler                EventHandler[T, P]  // 结构化事件回调
+	listened               int                 // 设置了事件监听的对象数
+	recorder               *Recorder           // 操作日志
 }
 
 // NewAOIManager 构造
-func NewAOIManager[T ObjID](width, height int, gridW, gridH int) (*AOIManager[T], error) {
-	return NewAOIManagerFrom[T](0, 0, width, height, gridW, gridH)
+func NewAOIManager[T ObjID, P Coord](width, height P, gridW, gridH P, opts ...Option) (*AOIManager[T, P], error) {
+	return NewAOIManagerFrom[T, P](0, 0, width, height, gridW, gridH, opts...)
 }
 
 // NewAOIManagerFrom 构造
 // x, y 可以是负数
-func NewAOIManagerFrom[T ObjID](x, y, width, height int, gridW, gridH int) (*AOIManager[T], error) {
+func NewAOIManagerFrom[T ObjID, P Coord](x, y, width, height P, gridW, gridH P, opts ...Option) (*AOIManager[T, P], error) {
+	return newAOIManager[T, P](x, y, 0, width, height, 1, gridW, gridH, 1, opts...)
+}
+
+// newAOIManager 构造, 2d时depth和gridD都为1, 只有1层
+func newAOIManager[T ObjID, P Coord](x, y, z, width, height, depth P, gridW, gridH, gridD P, opts ...Option) (*AOIManager[T, P], error) {
 	if gridH <= 0 || gridW <= 0 {
 		return nil, fmt.Errorf("gridH,gridW should not be 0")
 	}
 	if width <= 0 || height <= 0 {
 		return nil, fmt.Errorf("width, height should not be 0")
 	}
+	if gridD <= 0 || depth <= 0 {
+		return nil, fmt.Errorf("depth, gridD should not be 0")
+	}
+	o := options{radius: DefaultRadius}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.radius <= 0 {
+		return nil, fmt.Errorf("radius should be greater than 0")
+	}
+	if o.hex && o.wrap {
+		return nil, fmt.Errorf("hex and wrap can not be used together")
+	}
+	if o.hysteresis < 0 {
+		return nil, fmt.Errorf("hysteresis should not be negative")
+	}
 	maxX, maxY := x+width, y+height
 	// 列
 	col := int(math.Ceil(float64(width) / float64(gridW)))
 	// 行
 	row := int(math.Ceil(float64(height) / float64(gridH)))
-	m := &AOIManager[T]{
-		minX:  x,
-		minY:  y,
-		maxX:  maxX,
-		maxY:  maxY,
-		gridH: gridH,
-		gridW: gridW,
-		col:   col,
-		row:   row,
-		grids: make([]*Grid[T], 0, col*row),
-		objs:  make(map[T]*obj),
+	// 层
+	layer := int(math.Ceil(float64(depth) / float64(gridD)))
+	m := &AOIManager[T, P]{
+		minX:       x,
+		minY:       y,
+		maxX:       maxX,
+		maxY:       maxY,
+		gridH:      gridH,
+		gridW:      gridW,
+		col:        col,
+		row:        row,
+		minZ:       z,
+		maxZ:       z + depth,
+		gridD:      gridD,
+		layer:      layer,
+		radius:     o.radius,
+		length:     2*o.radius + 1,
+		hex:        o.hex,
+		wrap:       o.wrap,
+		visibility: o.visibility,
+		hysteresis: o.hysteresis,
+		objs:       make(map[T]*obj[T, P]),
+		typeCount:  make(map[ObjType]int),
+	}
+	if o.sparse {
+		m.sparse = make(map[int]*Grid[T, P])
+		return m, nil
 	}
+	m.grids = make([]*Grid[T, P], 0, layer*col*row)
 	m.init()
 	return m, nil
 }
-func (m *AOIManager[ObjID]) init() {
-	for row := 0; row < m.row; row++ {
-		for col := 0; col < m.col; col++ {
-			idx := m.gridIndex(row, col)
-			if len(m.grids) != idx {
-				panic(fmt.Sprintf("idx:%d len:%d", idx, len(m.grids)))
-			}
+func (m *AOIManager[ObjID, P]) init() {
+	for layer := 0; layer < m.layer; layer++ {
+		for row := 0; row < m.row; row++ {
+			for col := 0; col < m.col; col++ {
+				idx := m.layerGridIndex(layer, row, col)
+				if len(m.grids) != idx {
+					panic(fmt.Sprintf("idx:%d len:%d", idx, len(m.grids)))
+				}
 
-			gridMinX, gridMinY := m.minX+col*m.gridW, m.minY+row*m.gridH
-			gridMaxX, gridMaxY := gridMinX+m.gridW, gridMinY+m.gridH
-			if gridMaxX > m.maxX {
-				gridMaxX = m.maxX
+				m.grids = append(m.grids, m.newGridAt(layer, row, col))
 			}
-			if gridMaxY > m.maxY {
-				gridMaxY = m.maxY
-			}
-			grid := newGrid[ObjID](idx, gridMinX, gridMinY, gridMaxX, gridMaxY, row, col)
-			m.grids = append(m.grids, grid)
 		}
 	}
 
 	// 预保存周围的格子
-	for col := 0; col < m.col; col++ {
-		for row := 0; row < m.row; row++ {
-			// 当前格子的id
-			gird := m.grids[m.gridIndex(row, col)]
+	for _, grid := range m.grids {
+		m.foreachSurround(grid, func(idx int) {
+			grid.addSurroundGrid(m.grids[idx])
+		})
+	}
+}
 
-			// 周围9个格子
-			for i := 0; i < GridLength; i++ {
-				_row := row - 1 + i
-				if _row < 0 || _row >= m.row {
+// foreachSurround 遍历g周围格子的id(包括自己)
+// 2d时周围length*length个格子, 3d时周围length*length*length个格子
+func (m *AOIManager[ObjID, P]) foreachSurround(g *Grid[ObjID, P], f func(idx int)) {
+	// 环形地图比相邻块还小时, 取模后会重复
+	if m.wrap && (m.row < m.length || m.col < m.length) {
+		seen := make(map[int]struct{})
+		inner := f
+		f = func(idx int) {
+			if _, ok := seen[idx]; ok {
+				return
+			}
+			seen[idx] = struct{}{}
+			inner(idx)
+		}
+	}
+	for k := 0; k < m.length; k++ {
+		_layer := g.layer - m.radius + k
+		if _layer < 0 || _layer >= m.layer {
+			continue
+		}
+		if m.hex {
+			m.hexForeachSurround(_layer, g.row, g.col, f)
+			continue
+		}
+		for i := 0; i < m.length; i++ {
+			_row := g.row - m.radius + i
+			if m.wrap {
+				_row = wrapIndex(_row, m.row)
+			} else if _row < 0 || _row >= m.row {
+				continue
+			}
+			for j := 0; j < m.length; j++ {
+				_col := g.col - m.radius + j
+				if m.wrap {
+					_col = wrapIndex(_col, m.col)
+				} else if _col < 0 || _col >= m.col {
 					continue
 				}
-				for j := 0; j < GridLength; j++ {
-					_col := col - 1 + j
-					if _col < 0 || _col >= m.col {
-						continue
-					}
-					// 周围格子的id(包括自己)
-					surroundGrid := m.grids[m.gridIndex(_row, _col)]
-					gird.addSurroundGrid(surroundGrid)
-				}
+				f(m.layerGridIndex(_layer, _row, _col))
 			}
 		}
 	}
 }
 
+// newGridAt 创建layer层row行col列的格子
+func (m *AOIManager[ObjID, P]) newGridAt(layer, row, col int) *Grid[ObjID, P] {
+	var (
+		gridMinX, gridMinY, gridMaxX, gridMaxY P
+		gridRow, gridCol                       = row, col
+		surroundNum                            = m.length * m.length
+	)
+	if m.hex {
+		gridMinX, gridMinY, gridMaxX, gridMaxY = m.hexBoundingBox(row, col)
+		gridCol = offsetToAxialQ(row, col)
+		surroundNum = hexSurroundNum(m.radius)
+	} else {
+		gridMinX, gridMinY = m.minX+P(col)*m.gridW, m.minY+P(row)*m.gridH
+		gridMaxX, gridMaxY = gridMinX+m.gridW, gridMinY+m.gridH
+	}
+	if gridMinX < m.minX {
+		gridMinX = m.minX
+	}
+	if gridMinY < m.minY {
+		gridMinY = m.minY
+	}
+	if gridMaxX > m.maxX {
+		gridMaxX = m.maxX
+	}
+	if gridMaxY > m.maxY {
+		gridMaxY = m.maxY
+	}
+	gridMinZ := m.minZ + P(layer)*m.gridD
+	gridMaxZ := gridMinZ + m.gridD
+	if gridMaxZ > m.maxZ {
+		gridMaxZ = m.maxZ
+	}
+	layers := m.length
+	if m.layer < layers {
+		layers = m.layer
+	}
+	g := newGrid[ObjID, P](m.layerGridIndex(layer, row, col), gridMinX, gridMinY, gridMaxX, gridMaxY, gridRow, gridCol, layers*surroundNum)
+	g.layer, g.minZ, g.maxZ = layer, gridMinZ, gridMaxZ
+	return g
+}
+
 // Enter 进入，cb是因
 // eventType 只会是EnterView
-func (m *AOIManager[ObjID]) Enter(id ObjID, posX, posY int, ot ObjType, cb EventCallback[ObjID]) bool {
+// opts 可以设置对象的可见半径等, 监听的类型参数和管理器不一致时返回false
+func (m *AOIManager[ObjID, P]) Enter(id ObjID, posX, posY P, ot ObjType, cb EventCallback[ObjID], opts ...EnterOption) bool {
+	return m.enter(id, posX, posY, 0, ot, cb, opts...)
+}
+
+func (m *AOIManager[ObjID, P]) enter(id ObjID, posX, posY, posZ P, ot ObjType, cb EventCallback[ObjID], opts ...EnterOption) (ok bool) {
+	eo := enterOptions{layer: AllLayers, sight: AllLayers}
+	for _, opt := range opts {
+		opt(&eo)
+	}
+	// 回放时没有监听, 监听不一致时不记录
+	listener, ok := listenerOf[ObjID, P](&eo)
+	if !ok {
+		return false
+	}
+	if m.recorder != nil {
+		var rec *LogRecord[ObjID, P]
+		rec, cb = m.record(OpEnter, id, posX, posY, posZ, cb)
+		rec.Type = ot
+		defer func() { m.recordDone(rec, ok) }()
+	}
 	if _, ok := m.objs[id]; ok {
 		return false
 	}
 	var (
-		g          = m.PosAtGrid(posX, posY)
+		g          = m.acquireGrid(posX, posY, posZ)
 		isObserver = ot.IsObserver()
+		o          = &obj[ObjID, P]{id: id, x: posX, y: posY, z: posZ, ot: ot, viewRadius: eo.viewRadius, layer: eo.layer, sight: eo.sight}
 	)
-	g.add(id, isObserver)
-	o := &obj{g.id, posX, posY, ot}
-	m.objs[id] = o
+	m.insert(o, g)
+	if listener != nil {
+		o.listener = listener
+		m.listened++
+	}
+	if m.directed() {
+		m.emitEdges(nil, m.seeEdges(map[ObjID]int{id: g.id}), nil, &edgeActor[ObjID]{id, NoGrid, g.id, false}, nil)
+	}
+	cb = m.eventCallback(id, o, NoGrid, g.id, false, cb)
 	if cb == nil {
 		return true
 	}
 
+	filter := m.viewFilter(o, posX, posY, posZ)
 	for _, sg := range g.SurroundGrids() {
-		sg.invokeEvent(id, isObserver, EnterView, cb)
+		sg.invokeEvent(id, isObserver, EnterView, cb, filter)
 	}
 	return true
 }
 
+// insert 把对象加入格子g, 不通知事件
+func (m *AOIManager[ObjID, P]) insert(o *obj[ObjID, P], g *Grid[ObjID, P]) {
+	o.gridID = g.id
+	g.add(o.id, o.ot.IsObserver())
+	if o.masked() {
+		m.masked++
+	}
+	if m.visibility {
+		o.sees, o.seenBy = make(set[ObjID]), make(set[ObjID])
+	}
+	m.objs[o.id] = o
+	m.countType(o.ot, 1)
+	if o.viewRadius > 0 {
+		m.ranged++
+	}
+}
+
 // Leave 离开
 // event 只会是LeaveView
-func (m *AOIManager[ObjID]) Leave(id ObjID, cb EventCallback[ObjID]) bool {
+func (m *AOIManager[ObjID, P]) Leave(id ObjID, cb EventCallback[ObjID]) (ok bool) {
+	if m.recorder != nil {
+		var rec *LogRecord[ObjID, P]
+		rec, cb = m.record(OpLeave, id, 0, 0, 0, cb)
+		defer func() { m.recordDone(rec, ok) }()
+	}
 	o, ok := m.objs[id]
 	if !ok {
 		return false
 	}
 	var (
-		g          = m.grids[o.gridID]
+		g          = m.grid(o.gridID)
 		isObserver = o.ot.IsObserver()
 	)
+	defer m.releaseGrid(g)
+	// 离开的对象自己也要收到LeaveView, 删除之前通知
+	if m.directed() {
+		m.emitEdges(m.seeEdges(map[ObjID]int{id: g.id}), nil, nil, &edgeActor[ObjID]{id, g.id, NoGrid, false}, nil)
+	}
+	cb = m.eventCallback(id, o, g.id, NoGrid, false, cb)
+	// 先按离开前的计数决定是否过滤, 最后一个有可见半径的对象离开时也要过滤
+	filter := m.viewFilter(o, o.x, o.y, o.z)
 	g.del(id)
 	delete(m.objs, id)
+	m.countType(o.ot, -1)
+	if o.viewRadius > 0 {
+		m.ranged--
+	}
+	if o.listener != nil {
+		m.listened--
+	}
+	if o.masked() {
+		m.masked--
+	}
 
 	if cb == nil {
 		return true
 	}
 	for _, sg := range g.SurroundGrids() {
-		sg.invokeEvent(id, isObserver, LeaveView, cb)
+		sg.invokeEvent(id, isObserver, LeaveView, cb, filter)
 	}
 
 	return true
@@ -285,49 +543,97 @@ E: EnterView
 +----+----+----+----+
 |  L |  U |  U |  E |
 +----+----+----+----+
+
+有对象设置了可见半径时, 交集内的对象会根据移动前后的距离变成EnterView或LeaveView
 */
-func (m *AOIManager[ObjID]) Move(id ObjID, toPosX, toPosY int, cb EventCallback[ObjID]) bool {
+func (m *AOIManager[ObjID, P]) Move(id ObjID, toPosX, toPosY P, cb EventCallback[ObjID]) bool {
+	return m.move(id, toPosX, toPosY, 0, false, cb)
+}
+
+/*
+Teleport 瞬移
+
+和Move的区别是不区分前后相邻格子的交集, 也不会通知UpdateView:
+1. 跨格子时先通知原来相邻格子内的对象LeaveView, 再通知到达的相邻格子内的对象EnterView,
+前后都在范围内的对象会依次收到LeaveView和EnterView
+2. 在同一个格子内瞬移时事件和Move一样
+
+事件的Event.Teleport为true, 可以用来区分瞬移和走路
+*/
+func (m *AOIManager[ObjID, P]) Teleport(id ObjID, toPosX, toPosY P, cb EventCallback[ObjID]) bool {
+	return m.move(id, toPosX, toPosY, 0, true, cb)
+}
+
+// move teleport为true时跨格子不再区分交集, 见Teleport
+func (m *AOIManager[ObjID, P]) move(id ObjID, toPosX, toPosY, toPosZ P, teleport bool, cb EventCallback[ObjID]) (ok bool) {
+	if m.recorder != nil {
+		op := OpMove
+		if teleport {
+			op = OpTeleport
+		}
+		var rec *LogRecord[ObjID, P]
+		rec, cb = m.record(op, id, toPosX, toPosY, toPosZ, cb)
+		defer func() { m.recordDone(rec, ok) }()
+	}
 	o, ok := m.objs[id]
 	if !ok {
 		return false
 	}
 
+	var before map[seeEdge[ObjID]]struct{}
+	if m.directed() {
+		before = m.seeEdges(map[ObjID]int{id: o.gridID})
+	}
 	var (
-		fromGrid   = m.grids[o.gridID]
-		toGrid     = m.PosAtGrid(toPosX, toPosY)
-		isTrigger  = o.ot.IsTrigger()
-		isObserver = o.ot.IsObserver()
+		isObserver          = o.ot.IsObserver()
+		fromX, fromY, fromZ = o.x, o.y, o.z
+		fromGrid, toGrid    = m.relocate(id, o, toPosX, toPosY, toPosZ)
 	)
 
-	// 更新坐标
-	o.x, o.y, o.gridID = toPosX, toPosY, toGrid.id
-	if fromGrid.id != toGrid.id {
-		fromGrid.del(id)
-		toGrid.add(id, isObserver)
+	defer m.releaseGrid(fromGrid)
+
+	if before != nil {
+		m.emitMove(id, before, fromGrid.id, toGrid.id, teleport)
 	}
 
+	cb = m.eventCallback(id, o, fromGrid.id, toGrid.id, teleport, cb)
 	if cb == nil {
 		return true
 	}
 
+	var (
+		was = m.viewFilter(o, fromX, fromY, fromZ)
+		now = m.viewFilter(o, toPosX, toPosY, toPosZ)
+	)
+
 	// 情况1. 在同一个格子内移动
 	if fromGrid.id == toGrid.id {
-		if isTrigger {
-			for _, sg := range toGrid.SurroundGrids() {
-				sg.invokeEvent(id, false, UpdateView, cb)
-			}
+		for _, sg := range toGrid.SurroundGrids() {
+			m.invokeMoveEvent(sg, id, o, was, now, cb)
+		}
+		return true
+	}
+
+	// 瞬移. 先通知原来的相邻格子离开, 再通知到达的相邻格子进入
+	if teleport {
+		for _, sg := range fromGrid.SurroundGrids() {
+			sg.invokeEvent(id, isObserver, LeaveView, cb, was)
+		}
+		for _, sg := range toGrid.SurroundGrids() {
+			sg.invokeEvent(id, isObserver, EnterView, cb, now)
 		}
 		return true
 	}
 
-	// 情况2. 跨越3个格子
-	if abs(toGrid.row-fromGrid.row) >= GridLength ||
-		abs(toGrid.col-fromGrid.col) >= GridLength {
+	// 情况2. 跨越length个格子, 前后两个相邻块没有交集
+	if m.indexDelta(toGrid.row, fromGrid.row, m.row) >= m.length ||
+		m.indexDelta(toGrid.col, fromGrid.col, m.col) >= m.length ||
+		abs(toGrid.layer-fromGrid.layer) >= m.length {
 		for _, sg := range toGrid.SurroundGrids() {
-			sg.invokeEvent(id, isObserver, EnterView, cb)
+			sg.invokeEvent(id, isObserver, EnterView, cb, now)
 		}
 		for _, sg := range fromGrid.SurroundGrids() {
-			sg.invokeEvent(id, isObserver, LeaveView, cb)
+			sg.invokeEvent(id, isObserver, LeaveView, cb, was)
 		}
 		return true
 	}
@@ -336,96 +642,251 @@ func (m *AOIManager[ObjID]) Move(id ObjID, toPosX, toPosY int, cb EventCallback[
 	// 1) 新进入的格子 = 到达的九宫格-原来所在的九宫格
 	for _, grid := range toGrid.SurroundGrids() {
 		if !fromGrid.isSurround(grid.id) {
-			grid.invokeEvent(id, isObserver, EnterView, cb)
+			grid.invokeEvent(id, isObserver, EnterView, cb, now)
 		}
 	}
 
 	// 2) 没变的格子 = 原来所在的九宫格和到达的九宫格取交集
-	// 只有trigger才会通知
-	if isTrigger {
-		for _, grid := range fromGrid.SurroundGrids() {
-			if toGrid.isSurround(grid.id) {
-				grid.invokeEvent(id, false, UpdateView, cb)
-			}
+	for _, grid := range fromGrid.SurroundGrids() {
+		if toGrid.isSurround(grid.id) {
+			m.invokeMoveEvent(grid, id, o, was, now, cb)
 		}
 	}
 
 	// 3) 离开的格子 = 原来所在的九宫格-到达的九宫格
 	for _, grid := range fromGrid.SurroundGrids() {
 		if !toGrid.isSurround(grid.id) {
-			grid.invokeEvent(id, isObserver, LeaveView, cb)
+			grid.invokeEvent(id, isObserver, LeaveView, cb, was)
 		}
 	}
 
 	return true
 }
 
+// relocate 更新对象的坐标和所在格子, 不通知事件, 返回前后所在的格子
+// 稀疏模式下调用方负责释放fromGrid
+func (m *AOIManager[ObjID, P]) relocate(id ObjID, o *obj[ObjID, P], toPosX, toPosY, toPosZ P) (fromGrid, toGrid *Grid[ObjID, P]) {
+	fromGrid = m.grid(o.gridID)
+	if m.hysteresis > 0 && m.inHysteresis(fromGrid, toPosX, toPosY, toPosZ) {
+		toGrid = fromGrid
+	} else {
+		toGrid = m.acquireGrid(toPosX, toPosY, toPosZ)
+	}
+	o.x, o.y, o.z, o.gridID = toPosX, toPosY, toPosZ, toGrid.id
+	if fromGrid.id != toGrid.id {
+		fromGrid.del(id)
+		toGrid.add(id, o.ot.IsObserver())
+	}
+	return fromGrid, toGrid
+}
+
+// invokeMoveEvent 通知移动前后都在相邻范围内的格子
+// 没有可见半径时只有trigger才会通知UpdateView
+// 有可见半径时根据移动前后是否可见通知EnterView, LeaveView或UpdateView
+func (m *AOIManager[ObjID, P]) invokeMoveEvent(g *Grid[ObjID, P], id ObjID, o *obj[ObjID, P], was, now func(other ObjID) bool, cb EventCallback[ObjID]) {
+	isTrigger := o.ot.IsTrigger()
+	if was == nil {
+		if isTrigger {
+			g.invokeEvent(id, false, UpdateView, cb, nil)
+		}
+		return
+	}
+
+	others := g.observers
+	if o.ot.IsObserver() {
+		others = g.objs
+	}
+	for other := range others {
+		if other == id {
+			continue
+		}
+		w, n := was(other), now(other)
+		switch {
+		case w && n:
+			// 可能只是o能看到other, other自己看不到o时不通知UpdateView
+			if isTrigger && g.observers.Contains(other) && m.sees(m.objs[other], o) {
+				cb(UpdateView, other)
+			}
+		case n:
+			cb(EnterView, other)
+		case w:
+			cb(LeaveView, other)
+		}
+	}
+}
+
+// directed 是否需要从观察者的视角通知监听或者维护可见集合
+func (m *AOIManager[ObjID, P]) directed() bool {
+	return m.listened > 0 || m.visibility
+}
+
+// emitMove 从观察者的视角通知移动前后观察关系的变化
+// 跨格子瞬移时前后都看得到的观察者也依次收到LeaveView和EnterView, 和Teleport一致
+func (m *AOIManager[ObjID, P]) emitMove(id ObjID, before map[seeEdge[ObjID]]struct{}, fromGrid, toGrid int, teleport bool) {
+	var (
+		after = m.seeEdges(map[ObjID]int{id: toGrid})
+		act   = &edgeActor[ObjID]{id, fromGrid, toGrid, teleport}
+	)
+	if teleport && fromGrid != toGrid {
+		m.emitEdges(before, nil, nil, act, nil)
+		m.emitEdges(nil, after, nil, act, nil)
+		return
+	}
+	m.emitEdges(before, after, map[ObjID]int{id: fromGrid}, act, nil)
+}
+
+// sees observer能否看到target: target在observer的可见半径内并且符合可见规则
+func (m *AOIManager[ObjID, P]) sees(observer, target *obj[ObjID, P]) bool {
+	if observer.viewRadius > 0 &&
+		!inRadius(m.posDistanceSq(observer.x, observer.y, observer.z, target.x, target.y, target.z), observer.viewRadius) {
+		return false
+	}
+	return m.canSee(observer, target)
+}
+
+// viewFilter 按可见半径和可见规则过滤相邻格子内的对象, o在(x,y)时和other是否可见
+// 任意一方作为观察者在自己的可见半径内并且能看到另一方即可见
+// 没有对象设置可见半径和层, 也没有自定义可见规则时返回nil, 不过滤
+func (m *AOIManager[ObjID, P]) viewFilter(o *obj[ObjID, P], x, y, z P) func(other ObjID) bool {
+	if m.ranged == 0 && m.masked == 0 && m.visibleFunc == nil {
+		return nil
+	}
+	isObserver := o.ot.IsObserver()
+	return func(other ObjID) bool {
+		t := m.objs[other]
+		d := m.posDistanceSq(x, y, z, t.x, t.y, t.z)
+		return (isObserver && inRadius(d, o.viewRadius) && m.canSee(o, t)) ||
+			(t.ot.IsObserver() && inRadius(d, t.viewRadius) && m.canSee(t, o))
+	}
+}
+
 // ObjGrid obj所在的格子
-func (m *AOIManager[ObjID]) ObjGrid(id ObjID) *Grid[ObjID] {
+func (m *AOIManager[ObjID, P]) ObjGrid(id ObjID) *Grid[ObjID, P] {
 	o, ok := m.objs[id]
 	if !ok {
 		return nil
 	}
-	return m.grids[o.gridID]
+	return m.grid(o.gridID)
 }
 
 // PosAtGrid 坐标所在的格子
 // 出地图边界给返回边界的格子
-func (m *AOIManager[ObjID]) PosAtGrid(posX, posY int) *Grid[ObjID] {
-	return m.grids[m.posAtGridIndex(posX, posY)]
+// 稀疏模式下格子还没有分配时返回nil
+func (m *AOIManager[ObjID, P]) PosAtGrid(posX, posY P) *Grid[ObjID, P] {
+	return m.grid(m.posAtGridIndex(posX, posY, 0))
 }
 
 // AllGrids 所有格子
-func (m *AOIManager[ObjID]) AllGrids() []*Grid[ObjID] {
+// 稀疏模式下只返回已分配的格子, 按格子id排序
+func (m *AOIManager[ObjID, P]) AllGrids() []*Grid[ObjID, P] {
+	if m.sparse != nil {
+		return m.sparseGrids()
+	}
 	return m.grids
 }
 
 // Clear 清空
-func (m *AOIManager[ObjID]) Clear() {
-	m.objs = make(map[ObjID]*obj)
+func (m *AOIManager[ObjID, P]) Clear() {
+	if m.recorder != nil {
+		var id ObjID
+		rec, _ := m.record(OpClear, id, 0, 0, 0, nil)
+		defer m.recordDone(rec, true)
+	}
+	m.objs = make(map[ObjID]*obj[ObjID, P])
+	m.typeCount = make(map[ObjType]int)
+	m.ranged = 0
+	m.listened = 0
+	m.masked = 0
+	if m.sparse != nil {
+		m.sparse = make(map[int]*Grid[ObjID, P])
+		return
+	}
 	for _, v := range m.grids {
 		v.clear()
 	}
 }
 
 // String 格式化输出
-func (m *AOIManager[ObjID]) String() string {
+func (m *AOIManager[ObjID, P]) String() string {
+	if m.sparse != nil {
+		return m.sparseString()
+	}
 	str := ""
-	for row := 0; row < m.row; row++ {
-		for col := 0; col < m.col; col++ {
-			str += fmt.Sprintf("%10s ", m.grids[m.gridIndex(row, col)])
+	for layer := 0; layer < m.layer; layer++ {
+		if m.layer > 1 {
+			str += fmt.Sprintf("layer%d:\n", layer)
+		}
+		for row := 0; row < m.row; row++ {
+			for col := 0; col < m.col; col++ {
+				str += fmt.Sprintf("%10s ", m.grids[m.layerGridIndex(layer, row, col)])
+			}
+			str += "\n"
 		}
-		str += "\n"
 	}
 	return str
 }
 
-func abs(a int) int {
+func abs[P Coord](a P) P {
 	if a > 0 {
 		return a
 	}
 	return -a
 }
 
-func (m *AOIManager[ObjID]) gridIndex(row, col int) int {
-	return row*m.col + col
+// distanceSq 距离的平方
+func distanceSq[P Coord](x1, y1, x2, y2 P) float64 {
+	dx, dy := float64(x1)-float64(x2), float64(y1)-float64(y2)
+	return dx*dx + dy*dy
+}
+
+// inRadius 距离平方为d时是否在半径内, 半径<=0不限制
+func inRadius(d float64, radius float64) bool {
+	return radius <= 0 || d <= radius*radius
+}
+
+func (m *AOIManager[ObjID, P]) gridIndex(row, col int) int {
+	return m.layerGridIndex(0, row, col)
+}
+
+func (m *AOIManager[ObjID, P]) layerGridIndex(layer, row, col int) int {
+	return (layer*m.row+row)*m.col + col
 }
 
-func (m *AOIManager[ObjID]) posAtGridIndex(posX, posY int) int {
-	var col, row int
-	if posX <= m.minX {
-		col = 0
-	} else if posX >= m.maxX {
-		col = m.col - 1
+// gridLayerRowCol 格子id对应的层行列
+func (m *AOIManager[ObjID, P]) gridLayerRowCol(idx int) (int, int, int) {
+	return idx / (m.row * m.col), idx / m.col % m.row, idx % m.col
+}
+
+func (m *AOIManager[ObjID, P]) posAtGridIndex(posX, posY, posZ P) int {
+	var row, col int
+	if m.hex {
+		row, col = m.hexPosAtRowCol(posX, posY)
 	} else {
-		col = int(float64(posX-m.minX) / float64(m.gridW))
+		if m.wrap {
+			posX, posY = wrapCoord(posX, m.minX, m.maxX), wrapCoord(posY, m.minY, m.maxY)
+		}
+		col = axisIndex(posX, m.minX, m.maxX, m.gridW, m.col)
+		row = axisIndex(posY, m.minY, m.maxY, m.gridH, m.row)
 	}
-	if posY <= m.minY {
-		row = 0
-	} else if posY >= m.maxY {
-		row = m.row - 1
-	} else {
-		row = int(float64(posY-m.minY) / float64(m.gridH))
+	layer := 0
+	if m.layer > 1 {
+		layer = axisIndex(posZ, m.minZ, m.maxZ, m.gridD, m.layer)
+	}
+	return m.layerGridIndex(layer, row, col)
+}
+
+// axisIndex 坐标在某个轴上的格子下标, 超出范围的返回边界的格子
+func axisIndex[P Coord](pos, min, max, size P, n int) int {
+	if pos <= min {
+		return 0
+	}
+	if pos >= max {
+		return n - 1
+	}
+	idx := int(float64(pos-min) / float64(size))
+	// 浮点坐标的精度误差
+	if idx >= n {
+		idx = n - 1
 	}
-	return m.gridIndex(row, col)
+	return idx
 }
@@ -0,0 +1,182 @@
+package aoi
+
+import "iter"
+
+/*
+3d aoi
+
+在九宫格的基础上增加z轴, 格子按层(layer)叠起来,
+周围的格子变成3x3x3(相邻半径可以用WithRadius配置)。
+例如地下城的多层楼, 飞行单位和几百米以下的地面单位互相看不到。
+
+AOI事件规则和2d完全一致。
+*/
+
+// AOIManager3D 3d aoi管理器
+type AOIManager3D[T ObjID, P Coord] struct {
+	m *AOIManager[T, P]
+}
+
+// NewAOIManager3D 构造
+func NewAOIManager3D[T ObjID, P Coord](width, height, depth P, gridW, gridH, gridD P, opts ...Option) (*AOIManager3D[T, P], error) {
+	return NewAOIManager3DFrom[T, P](0, 0, 0, width, height, depth, gridW, gridH, gridD, opts...)
+}
+
+// NewAOIManager3DFrom 构造
+// x, y, z 可以是负数
+func NewAOIManager3DFrom[T ObjID, P Coord](x, y, z, width, height, depth P, gridW, gridH, gridD P, opts ...Option) (*AOIManager3D[T, P], error) {
+	m, err := newAOIManager[T, P](x, y, z, width, height, depth, gridW, gridH, gridD, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &AOIManager3D[T, P]{m: m}, nil
+}
+
+// Enter 进入
+// eventType 只会是EnterView
+func (m *AOIManager3D[T, P]) Enter(id T, posX, posY, posZ P, ot ObjType, cb EventCallback[T], opts ...EnterOption) bool {
+	return m.m.enter(id, posX, posY, posZ, ot, cb, opts...)
+}
+
+// Leave 离开
+// event 只会是LeaveView
+func (m *AOIManager3D[T, P]) Leave(id T, cb EventCallback[T]) bool {
+	return m.m.Leave(id, cb)
+}
+
+// Move 移动
+func (m *AOIManager3D[T, P]) Move(id T, toPosX, toPosY, toPosZ P, cb EventCallback[T]) bool {
+	return m.m.move(id, toPosX, toPosY, toPosZ, false, cb)
+}
+
+// Teleport 瞬移, 同AOIManager.Teleport
+func (m *AOIManager3D[T, P]) Teleport(id T, toPosX, toPosY, toPosZ P, cb EventCallback[T]) bool {
+	return m.m.move(id, toPosX, toPosY, toPosZ, true, cb)
+}
+
+// ObjGrid obj所在的格子
+func (m *AOIManager3D[T, P]) ObjGrid(id T) *Grid[T, P] {
+	return m.m.ObjGrid(id)
+}
+
+// PosAtGrid 坐标所在的格子
+// 出地图边界给返回边界的格子
+func (m *AOIManager3D[T, P]) PosAtGrid(posX, posY, posZ P) *Grid[T, P] {
+	return m.m.grid(m.m.posAtGridIndex(posX, posY, posZ))
+}
+
+// AllGrids 所有格子
+func (m *AOIManager3D[T, P]) AllGrids() []*Grid[T, P] {
+	return m.m.AllGrids()
+}
+
+// Clear 清空
+func (m *AOIManager3D[T, P]) Clear() {
+	m.m.Clear()
+}
+
+// String 格式化输出
+func (m *AOIManager3D[T, P]) String() string {
+	return m.m.String()
+}
+
+// SetEventHandler 设置结构化事件回调, nil取消
+func (m *AOIManager3D[T, P]) SetEventHandler(h EventHandler[T, P]) {
+	m.m.SetEventHandler(h)
+}
+
+// SetListener 设置对象的事件监听, nil取消
+func (m *AOIManager3D[T, P]) SetListener(id T, l Listener[T, P]) bool {
+	return m.m.SetListener(id, l)
+}
+
+// SetHandler 设置对象的事件回调, nil取消
+func (m *AOIManager3D[T, P]) SetHandler(id T, h EventHandler[T, P]) bool {
+	return m.m.SetHandler(id, h)
+}
+
+// SetObjType 修改对象类型
+func (m *AOIManager3D[T, P]) SetObjType(id T, ot ObjType, cb EventCallback[T]) bool {
+	return m.m.SetObjType(id, ot, cb)
+}
+
+// Has 对象是否存在
+func (m *AOIManager3D[T, P]) Has(id T) bool {
+	return m.m.Has(id)
+}
+
+// Pos 对象的坐标, 对象不存在时ok为false
+func (m *AOIManager3D[T, P]) Pos(id T) (x, y, z P, ok bool) {
+	o, ok := m.m.objs[id]
+	if !ok {
+		return x, y, z, false
+	}
+	return o.x, o.y, o.z, true
+}
+
+// Type 对象的类型, 对象不存在时ok为false
+func (m *AOIManager3D[T, P]) Type(id T) (ObjType, bool) {
+	return m.m.Type(id)
+}
+
+// Len 对象总数
+func (m *AOIManager3D[T, P]) Len() int {
+	return m.m.Len()
+}
+
+// Count 类型和ot有交集的对象数, ot为0时是对象总数
+func (m *AOIManager3D[T, P]) Count(ot ObjType) int {
+	return m.m.Count(ot)
+}
+
+// ForeachObj 遍历所有对象, f返回false时停止
+func (m *AOIManager3D[T, P]) ForeachObj(f func(id T, x, y, z P, ot ObjType) bool) {
+	for id, o := range m.m.objs {
+		if !f(id, o.x, o.y, o.z, o.ot) {
+			break
+		}
+	}
+}
+
+// All 所有对象
+func (m *AOIManager3D[T, P]) All() iter.Seq[T] {
+	return m.m.All()
+}
+
+// Neighbors id所在格子的相邻格子内的其他对象, 不包括自己
+func (m *AOIManager3D[T, P]) Neighbors(id T) iter.Seq[T] {
+	return m.m.Neighbors(id)
+}
+
+// State 当前状态的快照
+func (m *AOIManager3D[T, P]) State() *State[T, P] {
+	return m.m.State()
+}
+
+// Restore3D 从快照恢复3d管理器, 不通知任何事件
+func Restore3D[T ObjID, P Coord](s *State[T, P]) (*AOIManager3D[T, P], error) {
+	m, err := Restore(s)
+	if err != nil {
+		return nil, err
+	}
+	return &AOIManager3D[T, P]{m: m}, nil
+}
+
+// SetRecorder 设置操作日志, nil取消
+func (m *AOIManager3D[T, P]) SetRecorder(r *Recorder) {
+	m.m.SetRecorder(r)
+}
+
+// Resize 修改地图范围, 格子大小不变
+func (m *AOIManager3D[T, P]) Resize(x, y, z, width, height, depth P, h EventHandler[T, P]) error {
+	c := m.m.config()
+	c.MinX, c.MinY, c.MinZ, c.Width, c.Height, c.Depth = x, y, z, width, height, depth
+	return m.m.rebuild(&c, h)
+}
+
+// Regrid 修改格子大小, 地图范围不变
+func (m *AOIManager3D[T, P]) Regrid(gridW, gridH, gridD P, h EventHandler[T, P]) error {
+	c := m.m.config()
+	c.GridW, c.GridH, c.GridD = gridW, gridH, gridD
+	return m.m.rebuild(&c, h)
+}
@@ -0,0 +1,103 @@
+package aoi
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI3D_Init(t *testing.T) {
+	_, err := NewAOIManager3D[int](100, 100, 0, 10, 10, 10)
+	require.NotNil(t, err)
+
+	a, err := NewAOIManager3D[int](100, 100, 100, 10, 10, 10)
+	require.Nil(t, err)
+	require.Len(t, a.AllGrids(), 1000)
+	require.Len(t, a.PosAtGrid(0, 0, 0).SurroundGrids(), 8)
+	require.Len(t, a.PosAtGrid(50, 50, 50).SurroundGrids(), 27)
+
+	g := a.PosAtGrid(15, 25, 35)
+	row, col := g.RowCol()
+	require.Equal(t, []int{3, 2, 1}, []int{g.Layer(), row, col})
+	minZ, maxZ := g.ZRange()
+	require.Equal(t, []int{30, 40}, []int{minZ, maxZ})
+	require.Equal(t, 3*100+2*10+1, g.ID())
+
+	// 2d只有一层
+	a2, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	require.Len(t, a2.PosAtGrid(50, 50).SurroundGrids(), 9)
+}
+
+func TestAOI3D_Layers(t *testing.T) {
+	a, err := NewAOIManager3D[int](100, 100, 100, 10, 10, 10)
+	require.Nil(t, err)
+
+	shouldNotCall := func(event EventType, observer int) {
+		require.Fail(t, "should not call")
+	}
+
+	// 地面单位
+	a.Enter(1, 50, 50, 0, TriggerAndObserver, nil)
+	// 飞行单位在几层之上, 看不到地面单位
+	a.Enter(2, 50, 50, 80, TriggerAndObserver, shouldNotCall)
+
+	var events []EventType
+	cb := func(event EventType, other int) {
+		require.Equal(t, 1, other)
+		events = append(events, event)
+	}
+	a.Move(2, 50, 50, 15, cb)
+	a.Move(2, 55, 55, 5, cb)
+	a.Move(2, 55, 55, 19, cb)
+	a.Move(2, 55, 55, 25, cb)
+	require.Equal(t, []EventType{EnterView, UpdateView, UpdateView, LeaveView}, events)
+
+	_shouldCall := testSet{2: {}}
+	a.Move(1, 50, 50, 20, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	_shouldCall = testSet{2: {}}
+	a.Leave(1, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	a.Clear()
+	a.Enter(1, 50, 50, 0, TriggerAndObserver, shouldNotCall)
+}
+
+func TestAOI3D_Sparse(t *testing.T) {
+	const (
+		w, h, d = 100, 100, 100
+		num     = 200
+	)
+	dense, err := NewAOIManager3D[int](w, h, d, 10, 10, 10)
+	require.Nil(t, err)
+	sparse, err := NewAOIManager3D[int](w, h, d, 10, 10, 10, WithSparse())
+	require.Nil(t, err)
+
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		x, y, z, ot := rand.Intn(w), rand.Intn(h), rand.Intn(d), ots[rand.Intn(len(ots))]
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		dense.Enter(id, x, y, z, ot, r1.callFunc())
+		sparse.Enter(id, x, y, z, ot, r2.callFunc())
+		r1.requireEqual(t, r2, "enter")
+	}
+	for i := 0; i < 2000; i++ {
+		id := rand.Intn(num)
+		x, y, z := rand.Intn(w), rand.Intn(h), rand.Intn(d)
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		dense.Move(id, x, y, z, r1.callFunc())
+		sparse.Move(id, x, y, z, r2.callFunc())
+		r1.requireEqual(t, r2, "move")
+		require.Equal(t, dense.ObjGrid(id).ID(), sparse.ObjGrid(id).ID())
+	}
+	for id := 0; id < num; id++ {
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		dense.Leave(id, r1.callFunc())
+		sparse.Leave(id, r2.callFunc())
+		r1.requireEqual(t, r2, "leave")
+	}
+	require.Len(t, sparse.AllGrids(), 0)
+}
@@ -368,3 +368,196 @@ func BenchmarkAOI_Move(b *testing.B) {
 		_ = a.Move(i%obj, x, y, cb)
 	}
 }
+
+func TestAOI_Radius(t *testing.T) {
+	_, err := NewAOIManager[int](100, 100, 10, 10, WithRadius(0))
+	require.NotNil(t, err)
+
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithRadius(2))
+	require.Nil(t, err)
+	require.EqualValues(t, 9, len(a.grids[0].SurroundGrids()))
+	require.EqualValues(t, 25, len(a.PosAtGrid(50, 50).SurroundGrids()))
+	require.True(t, a.PosAtGrid(50, 50).isSurround(a.PosAtGrid(70, 70).ID()))
+	require.False(t, a.PosAtGrid(50, 50).isSurround(a.PosAtGrid(80, 50).ID()))
+
+	a.Enter(1, 0, 0, TriggerAndObserver, nil)
+	a.Enter(2, 20, 20, TriggerAndObserver, nil)
+	a.Enter(3, 40, 40, TriggerAndObserver, nil)
+
+	_shouldCall := testSet{1: {}, 2: {}}
+	a.Enter(4, 10, 10, TriggerAndObserver, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	var enter, leave, update []int
+	cb := func(event EventType, other int) {
+		switch event {
+		case EnterView:
+			enter = append(enter, other)
+		case LeaveView:
+			leave = append(leave, other)
+		case UpdateView:
+			update = append(update, other)
+		}
+	}
+	a.Move(4, 30, 30, cb)
+	require.ElementsMatch(t, []int{3}, enter)
+	require.ElementsMatch(t, []int{1}, leave)
+	require.ElementsMatch(t, []int{2}, update)
+
+	// 跨越5个格子
+	enter, leave, update = nil, nil, nil
+	a.Move(4, 90, 90, cb)
+	require.ElementsMatch(t, []int{}, enter)
+	require.ElementsMatch(t, []int{2, 3}, leave)
+	require.ElementsMatch(t, []int{}, update)
+}
+
+func TestAOI_ViewRadius(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	shouldNotCall := func(event EventType, observer int) {
+		require.Fail(t, "should not call")
+	}
+
+	a.Enter(1, 50, 50, TriggerAndObserver, nil, WithViewRadius(5))
+
+	_shouldCall := testSet{1: {}}
+	a.Enter(2, 52, 50, Trigger, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	// 同一个九宫格, 但是不在可见半径内
+	a.Enter(3, 58, 50, Trigger, shouldNotCall)
+
+	var events []EventType
+	cb := func(event EventType, other int) {
+		require.EqualValues(t, 1, other)
+		events = append(events, event)
+	}
+	a.Move(3, 54, 50, cb)
+	a.Move(3, 53, 50, cb)
+	a.Move(3, 65, 50, cb)
+	a.Move(3, 75, 50, shouldNotCall)
+	require.EqualValues(t, []EventType{EnterView, UpdateView, LeaveView}, events)
+
+	// 观察者移动
+	_shouldCall = testSet{2: {}}
+	a.Move(1, 60, 50, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	_shouldCall = testSet{3: {}}
+	a.Move(1, 70, 50, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	// 没有可见半径的观察者能看到相邻格子内所有对象
+	_shouldCall = testSet{1: {}, 2: {}, 3: {}}
+	a.Enter(4, 69, 59, TriggerAndObserver, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	_shouldCall = testSet{1: {}, 2: {}, 3: {}}
+	a.Leave(4, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+}
+
+func TestAOI_FloatCoord(t *testing.T) {
+	a, err := NewAOIManagerFrom[int, float32](-0.5, -0.5, 10, 10, 2.5, 2.5)
+	require.Nil(t, err)
+	require.EqualValues(t, 4, a.col)
+	require.EqualValues(t, 4, a.row)
+
+	g := a.PosAtGrid(1.99, 2.01)
+	row, col := g.RowCol()
+	require.EqualValues(t, 1, row)
+	require.EqualValues(t, 0, col)
+	minX, minY, maxX, maxY := g.BoundingBox()
+	require.Equal(t, []float32{-0.5, 2, 2, 4.5}, []float32{minX, minY, maxX, maxY})
+	require.EqualValues(t, a.col*a.row-1, a.PosAtGrid(9.4999, 9.4999).ID())
+
+	a.Enter(1, 0.1, 0.1, TriggerAndObserver, nil, WithViewRadius(1.5))
+	_shouldCall := testSet{1: {}}
+	a.Enter(2, 1.1, 1.1, Trigger, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	_shouldCall = testSet{1: {}}
+	a.Move(2, 1.2, 1.2, func(event EventType, other int) {
+		require.Equal(t, LeaveView, event)
+		_shouldCall.callFunc(t)(event, other)
+	})
+	_shouldCall.shouldEmpty(t)
+}
+
+func TestAOI_Teleport(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	require.False(t, a.Teleport(1, 0, 0, nil))
+
+	var events []Event[int, int]
+	a.SetEventHandler(func(e *Event[int, int]) {
+		events = append(events, *e)
+	})
+	l3 := &testListener{}
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 5, 5, TriggerAndObserver, nil)
+	a.Enter(3, 25, 25, Observer, nil, WithListener[int, int](l3))
+	a.Enter(4, 35, 15, TriggerAndObserver, nil)
+
+	// 跨格子: 原来和到达的相邻格子不区分交集, 先离开再进入
+	events = nil
+	*l3 = testListener{}
+	r := eventRecorder{}
+	var order []EventType
+	require.True(t, a.Teleport(1, 25, 15, func(event EventType, other int) {
+		order = append(order, event)
+		r.callFunc()(event, other)
+	}))
+	r.requireEqual(t, eventRecorder{LeaveView: {2, 3}, EnterView: {3, 4}}, "teleport")
+	require.Equal(t, []EventType{LeaveView, LeaveView, EnterView, EnterView}, order)
+	for _, e := range events {
+		require.True(t, e.Teleport)
+		require.Equal(t, 11, e.FromGrid)
+		require.Equal(t, 12, e.ToGrid)
+	}
+	require.Equal(t, []int{1}, l3.leave)
+	require.Equal(t, []int{1}, l3.enter)
+	require.ElementsMatch(t, []int{1, 4}, a.VisibleTo(3))
+	require.ElementsMatch(t, []int{4}, a.VisibleTo(1))
+
+	// 同一个格子内闪现和Move一样, 但是带瞬移标记
+	events = nil
+	r = eventRecorder{}
+	require.True(t, a.Teleport(1, 28, 18, r.callFunc()))
+	r.requireEqual(t, eventRecorder{UpdateView: {3, 4}}, "blink")
+	require.Len(t, events, 2)
+	for _, e := range events {
+		require.True(t, e.Teleport)
+		require.Equal(t, UpdateView, e.Type)
+	}
+
+	// Move不带瞬移标记
+	events = nil
+	a.Move(1, 29, 19, nil)
+	require.Len(t, events, 2)
+	for _, e := range events {
+		require.False(t, e.Teleport)
+	}
+}
+
+func TestAOI_ViewRadiusReceiver(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	shouldNotCall := func(event EventType, other int) {
+		require.Fail(t, "should not call", "%v %v", event, other)
+	}
+
+	// 最后一个有可见半径的对象离开时, 不能通知没有看到过的对象
+	a.Enter(1, 50, 50, Observer, shouldNotCall, WithViewRadius(5))
+	a.Enter(2, 58, 50, Trigger, shouldNotCall)
+	a.Leave(1, shouldNotCall)
+
+	// 2能看到3, 但是3在1的可见半径外, 1收不到3的UpdateView
+	a.Enter(1, 50, 50, Observer, nil, WithViewRadius(5))
+	a.Enter(3, 59, 50, TriggerAndObserver, nil, WithViewRadius(20))
+	r := eventRecorder{}
+	a.Move(3, 58, 51, r.callFunc())
+	r.requireEqual(t, eventRecorder{}, "update")
+}
@@ -0,0 +1,138 @@
+package aoi
+
+/*
+批量移动
+
+逐个调用Move时, 同一帧内离开又回来的对象会产生多余的LeaveView和EnterView。
+MoveMany先更新所有对象的坐标, 再比较这一帧前后每个观察者的视野, 只通知净变化:
+
+1. 之前看不到, 现在看得到: EnterView
+2. 之前看得到, 现在看不到: LeaveView
+3. 前后都看得到并且被看到的对象移动了: UpdateView
+
+和SetListener一样从观察者的视角通知, Receiver是观察者, Trigger是进出视野或者移动的对象,
+FromGrid和ToGrid是Trigger这一帧前后所在的格子, ObserverView为true。
+事件会回调SetEventHandler设置的handler, h和Receiver的Listener, 开启WithVisibility时同时更新可见集合。
+*/
+
+// MoveOp 移动操作
+type MoveOp[T ObjID, P Coord] struct {
+	ID   T
+	X, Y P
+}
+
+// seeEdge observer看到target
+type seeEdge[T ObjID] struct {
+	observer, target T
+}
+
+// MoveMany 批量移动, 返回移动的对象数, 不存在的对象忽略
+// 同一个对象出现多次时以最后一次为准
+func (m *AOIManager[ObjID, P]) MoveMany(ops []MoveOp[ObjID, P], h EventHandler[ObjID, P]) int {
+	h = m.withHandler(h)
+	fromGrids := make(map[ObjID]int, len(ops))
+	for _, op := range ops {
+		if o, ok := m.objs[op.ID]; ok {
+			if _, ok := fromGrids[op.ID]; !ok {
+				fromGrids[op.ID] = o.gridID
+			}
+		}
+	}
+	notified := h != nil || m.listened > 0 || m.visibility
+
+	var before map[seeEdge[ObjID]]struct{}
+	if notified {
+		before = m.seeEdges(fromGrids)
+	}
+	for _, op := range ops {
+		o, ok := m.objs[op.ID]
+		if !ok {
+			continue
+		}
+		fromGrid, _ := m.relocate(op.ID, o, op.X, op.Y, o.z)
+		m.releaseGrid(fromGrid)
+	}
+	if !notified {
+		return len(fromGrids)
+	}
+	after := m.seeEdges(fromGrids)
+
+	m.emitEdges(before, after, fromGrids, nil, h)
+	return len(fromGrids)
+}
+
+// edgeActor Enter, Leave, Move和Teleport的行动人, 作为Trigger时的FromGrid, ToGrid和Teleport以它为准
+type edgeActor[T ObjID] struct {
+	id               T
+	fromGrid, toGrid int
+	teleport         bool
+}
+
+// emitEdges 比较前后的观察关系, 从观察者的视角通知净变化
+// moved 移动过的对象和移动前所在的格子, 只有移动过的对象才通知UpdateView
+func (m *AOIManager[ObjID, P]) emitEdges(before, after map[seeEdge[ObjID]]struct{}, moved map[ObjID]int, act *edgeActor[ObjID], h EventHandler[ObjID, P]) {
+	var e Event[ObjID, P]
+	emit := func(event EventType, edge seeEdge[ObjID]) {
+		r, t := m.objs[edge.observer], m.objs[edge.target]
+		e = Event[ObjID, P]{
+			Type:        event,
+			Trigger:     edge.target,
+			TriggerType: t.ot,
+			TriggerX:    t.x, TriggerY: t.y, TriggerZ: t.z,
+			Receiver:     edge.observer,
+			ReceiverType: r.ot,
+			ReceiverX:    r.x, ReceiverY: r.y, ReceiverZ: r.z,
+			FromGrid:     t.gridID,
+			ToGrid:       t.gridID,
+			ObserverView: true,
+		}
+		if from, ok := moved[edge.target]; ok {
+			e.FromGrid = from
+		}
+		if act != nil && act.id == edge.target {
+			e.FromGrid, e.ToGrid, e.Teleport = act.fromGrid, act.toGrid, act.teleport
+		}
+		m.updateVisible(event, edge.observer, r, edge.target, t)
+		if h != nil {
+			h(&e)
+		}
+		if r.listener != nil {
+			notify(r.listener, &e)
+		}
+	}
+	for edge := range after {
+		if _, ok := before[edge]; !ok {
+			emit(EnterView, edge)
+		} else if _, ok := moved[edge.target]; ok {
+			emit(UpdateView, edge)
+		}
+	}
+	for edge := range before {
+		if _, ok := after[edge]; !ok {
+			emit(LeaveView, edge)
+		}
+	}
+}
+
+// seeEdges 和ids相关的所有观察关系, 每个方向按观察者自己的可见半径和可见规则判断
+func (m *AOIManager[ObjID, P]) seeEdges(ids map[ObjID]int) map[seeEdge[ObjID]]struct{} {
+	edges := make(map[seeEdge[ObjID]]struct{})
+	for id := range ids {
+		o := m.objs[id]
+		for _, sg := range m.grid(o.gridID).SurroundGrids() {
+			for other := range sg.objs {
+				if other == id {
+					continue
+				}
+				t := m.objs[other]
+				if o.ot.IsObserver() && t.ot.IsTrigger() && m.sees(o, t) {
+					edges[seeEdge[ObjID]{id, other}] = struct{}{}
+				}
+				if t.ot.IsObserver() && o.ot.IsTrigger() && m.sees(t, o) {
+					edges[seeEdge[ObjID]{other, id}] = struct{}{}
+				}
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,102 @@
+package aoi
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_MoveMany(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 25, 15, Trigger, nil)
+	a.Enter(3, 85, 85, Trigger, nil)
+
+	var events []Event[int, int]
+	h := func(e *Event[int, int]) {
+		events = append(events, *e)
+	}
+
+	// 离开又回来, 没有LeaveView和EnterView
+	require.Equal(t, 1, a.MoveMany([]MoveOp[int, int]{{2, 85, 15}, {2, 26, 16}, {4, 0, 0}}, h))
+	require.Equal(t, []Event[int, int]{{
+		Type:    UpdateView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 26, TriggerY: 16,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: 12, ToGrid: 12,
+		ObserverView: true,
+	}}, events)
+
+	// 观察者移动, 只有自己的视野变化, 不会收到UpdateView
+	events = nil
+	a.MoveMany([]MoveOp[int, int]{{1, 75, 75}, {3, 86, 86}}, h)
+	require.Len(t, events, 2)
+	require.ElementsMatch(t, []Event[int, int]{{
+		Type:    LeaveView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 26, TriggerY: 16,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 75, ReceiverY: 75,
+		FromGrid: 12, ToGrid: 12,
+		ObserverView: true,
+	}, {
+		Type:    EnterView,
+		Trigger: 3, TriggerType: Trigger, TriggerX: 86, TriggerY: 86,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 75, ReceiverY: 75,
+		FromGrid: 88, ToGrid: 88,
+		ObserverView: true,
+	}}, events)
+}
+
+func TestAOI_MoveManySeeList(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10, WithSparse())
+	require.Nil(t, err)
+
+	seeList := map[int]map[int]struct{}{}
+	handler := func(e *Event[int, int]) {
+		switch e.Type {
+		case EnterView:
+			_, ok := seeList[e.Receiver][e.Trigger]
+			require.False(t, ok)
+			seeList[e.Receiver][e.Trigger] = struct{}{}
+		case LeaveView:
+			_, ok := seeList[e.Receiver][e.Trigger]
+			require.True(t, ok)
+			delete(seeList[e.Receiver], e.Trigger)
+		case UpdateView:
+			_, ok := seeList[e.Receiver][e.Trigger]
+			require.True(t, ok)
+		}
+	}
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		seeList[id] = map[int]struct{}{}
+		a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil, WithHandler(handler))
+	}
+
+	for i := 0; i < 100; i++ {
+		var ops []MoveOp[int, int]
+		for j := 0; j < 50; j++ {
+			ops = append(ops, MoveOp[int, int]{rand.Intn(num), rand.Intn(w), rand.Intn(h)})
+		}
+		a.MoveMany(ops, nil)
+	}
+
+	for id, o := range a.objs {
+		expect := map[int]struct{}{}
+		if o.ot.IsObserver() {
+			a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+				if other != id && a.objs[other].ot.IsTrigger() {
+					expect[other] = struct{}{}
+				}
+				return true
+			})
+		}
+		require.Equal(t, expect, seeList[id], id)
+	}
+}
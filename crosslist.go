@@ -0,0 +1,337 @@
+package aoi
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+十字链表 aoi
+
+所有对象按x坐标和y坐标分别插入两条有序的双向链表。
+对象的相邻范围是以自己为中心, 边长为2*viewRange的正方形,
+查找时从对象所在节点沿两条链表同时向两边遍历, 先遍历完的那条轴上的对象就是候选集,
+再用另一条轴的坐标过滤。
+
+AOI事件规则和九宫格一致, 只是把相邻九宫格换成了相邻正方形。
+支持可见半径(WithViewRadius)和层(WithMask), 不支持对象监听。
+适合对象稀疏的大地图, 对象密集时移动的开销会变大。
+*/
+
+var _ Manager[int, int] = (*CrossListManager[int, int])(nil)
+
+// crossNode 链表节点, 头尾哨兵节点的obj为nil
+type crossNode[T ObjID, P Coord] struct {
+	prev, next *crossNode[T, P]
+	obj        *crossObj[T, P]
+}
+
+// crossObj 十字链表中的对象
+type crossObj[T ObjID, P Coord] struct {
+	id           T
+	x, y         P
+	ot           ObjType
+	viewRadius   float64
+	layer, sight uint64 // 所在的层和能看到的层
+	xNode, yNode crossNode[T, P]
+}
+
+// crossList 有序双向链表
+type crossList[T ObjID, P Coord] struct {
+	head, tail crossNode[T, P]
+	pos        func(o *crossObj[T, P]) P // 排序用的坐标
+}
+
+func (l *crossList[T, P]) init(pos func(o *crossObj[T, P]) P) {
+	l.head.next, l.tail.prev = &l.tail, &l.head
+	l.head.prev, l.tail.next = nil, nil
+	l.pos = pos
+}
+
+// insertAfter 把n插入到at后面
+func (l *crossList[T, P]) insertAfter(n, at *crossNode[T, P]) {
+	n.prev, n.next = at, at.next
+	at.next.prev = n
+	at.next = n
+}
+
+// remove 把n从链表中移除
+func (l *crossList[T, P]) remove(n *crossNode[T, P]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+}
+
+// insert 从头部开始找到位置插入
+func (l *crossList[T, P]) insert(n *crossNode[T, P]) {
+	at := &l.head
+	for at.next.obj != nil && l.pos(at.next.obj) <= l.pos(n.obj) {
+		at = at.next
+	}
+	l.insertAfter(n, at)
+}
+
+// fix 坐标变化后从当前位置向两边调整, 小范围移动时开销很小
+func (l *crossList[T, P]) fix(n *crossNode[T, P]) {
+	p := l.pos(n.obj)
+	// 向左
+	at := n.prev
+	for at.obj != nil && l.pos(at.obj) > p {
+		at = at.prev
+	}
+	if at != n.prev {
+		l.remove(n)
+		l.insertAfter(n, at)
+		return
+	}
+	// 向右
+	at = n.next
+	for at.obj != nil && l.pos(at.obj) <= p {
+		at = at.next
+	}
+	if at != n.next {
+		l.remove(n)
+		l.insertAfter(n, at.prev)
+	}
+}
+
+// crossWindow 从某个节点向两边遍历坐标差在r以内的对象
+type crossWindow[T ObjID, P Coord] struct {
+	left, right *crossNode[T, P]
+	center, r   P
+	pos         func(o *crossObj[T, P]) P
+}
+
+// next 窗口内的下一个对象, 遍历完返回nil
+func (w *crossWindow[T, P]) next() *crossObj[T, P] {
+	if o := w.left.obj; o != nil && w.center-w.pos(o) <= w.r {
+		w.left = w.left.prev
+		return o
+	}
+	if o := w.right.obj; o != nil && w.pos(o)-w.center <= w.r {
+		w.right = w.right.next
+		return o
+	}
+	return nil
+}
+
+// CrossListManager 十字链表aoi管理器
+type CrossListManager[T ObjID, P Coord] struct {
+	viewRange    P // 相邻范围, 以对象为中心边长2*viewRange的正方形
+	xList, yList crossList[T, P]
+	objs         map[T]*crossObj[T, P]
+	ranged       int // 设置了可见半径的对象数
+	masked       int // 设置了层的对象数
+}
+
+// NewCrossListManager 构造
+// viewRange 相邻范围, 和对象x,y坐标差都不超过viewRange的对象才相邻
+func NewCrossListManager[T ObjID, P Coord](viewRange P) (*CrossListManager[T, P], error) {
+	if viewRange <= 0 {
+		return nil, fmt.Errorf("viewRange should be greater than 0")
+	}
+	m := &CrossListManager[T, P]{
+		viewRange: viewRange,
+		objs:      make(map[T]*crossObj[T, P]),
+	}
+	m.xList.init(func(o *crossObj[T, P]) P { return o.x })
+	m.yList.init(func(o *crossObj[T, P]) P { return o.y })
+	return m, nil
+}
+
+// Enter 进入
+// eventType 只会是EnterView
+// 支持WithViewRadius和WithMask, 不支持对象监听, 传入WithListener或者WithHandler时返回false
+func (m *CrossListManager[T, P]) Enter(id T, posX, posY P, ot ObjType, cb EventCallback[T], opts ...EnterOption) bool {
+	if _, ok := m.objs[id]; ok {
+		return false
+	}
+	eo := enterOptions{layer: AllLayers, sight: AllLayers}
+	for _, opt := range opts {
+		opt(&eo)
+	}
+	if eo.listener != nil {
+		return false
+	}
+	o := &crossObj[T, P]{id: id, x: posX, y: posY, ot: ot, viewRadius: eo.viewRadius, layer: eo.layer, sight: eo.sight}
+	o.xNode.obj, o.yNode.obj = o, o
+	m.xList.insert(&o.xNode)
+	m.yList.insert(&o.yNode)
+	m.objs[id] = o
+	if o.viewRadius > 0 {
+		m.ranged++
+	}
+	if o.masked() {
+		m.masked++
+	}
+	if cb == nil {
+		return true
+	}
+
+	for _, other := range m.around(o) {
+		if m.visible(o, o.x, o.y, other) {
+			cb(EnterView, other.id)
+		}
+	}
+	return true
+}
+
+// Leave 离开
+// event 只会是LeaveView
+func (m *CrossListManager[T, P]) Leave(id T, cb EventCallback[T]) bool {
+	o, ok := m.objs[id]
+	if !ok {
+		return false
+	}
+	// 先按离开前的计数决定通知谁, 最后一个有可见半径的对象离开时也要过滤
+	var others []T
+	if cb != nil {
+		for _, other := range m.around(o) {
+			if m.visible(o, o.x, o.y, other) {
+				others = append(others, other.id)
+			}
+		}
+	}
+	m.xList.remove(&o.xNode)
+	m.yList.remove(&o.yNode)
+	delete(m.objs, id)
+	if o.viewRadius > 0 {
+		m.ranged--
+	}
+	if o.masked() {
+		m.masked--
+	}
+
+	for _, other := range others {
+		cb(LeaveView, other)
+	}
+	return true
+}
+
+// Move 移动
+// 和九宫格一样, 先通知EnterView, 再通知UpdateView, 最后通知LeaveView
+func (m *CrossListManager[T, P]) Move(id T, toPosX, toPosY P, cb EventCallback[T]) bool {
+	o, ok := m.objs[id]
+	if !ok {
+		return false
+	}
+	fromX, fromY := o.x, o.y
+	var before []*crossObj[T, P]
+	if cb != nil {
+		before = m.around(o)
+	}
+
+	o.x, o.y = toPosX, toPosY
+	m.xList.fix(&o.xNode)
+	m.yList.fix(&o.yNode)
+
+	if cb == nil {
+		return true
+	}
+
+	was := make(map[T]struct{}, len(before))
+	for _, other := range before {
+		if m.visible(o, fromX, fromY, other) {
+			was[other.id] = struct{}{}
+		}
+	}
+	var updates []T
+	for _, other := range m.around(o) {
+		if !m.visible(o, toPosX, toPosY, other) {
+			continue
+		}
+		if _, ok := was[other.id]; !ok {
+			cb(EnterView, other.id)
+			continue
+		}
+		delete(was, other.id)
+		// 只通知能看到行动人的观察者
+		if o.ot.IsTrigger() && other.ot.IsObserver() && m.sees(other, o) {
+			updates = append(updates, other.id)
+		}
+	}
+	for _, other := range updates {
+		cb(UpdateView, other)
+	}
+	for _, other := range before {
+		if _, ok := was[other.id]; ok {
+			cb(LeaveView, other.id)
+		}
+	}
+	return true
+}
+
+// Clear 清空
+func (m *CrossListManager[T, P]) Clear() {
+	m.objs = make(map[T]*crossObj[T, P])
+	m.ranged, m.masked = 0, 0
+	m.xList.init(m.xList.pos)
+	m.yList.init(m.yList.pos)
+}
+
+// String 格式化输出, 按x坐标排序
+func (m *CrossListManager[T, P]) String() string {
+	var sb strings.Builder
+	for n := m.xList.head.next; n.obj != nil; n = n.next {
+		sb.WriteString(fmt.Sprintf("(%v:%v,%v) ", n.obj.id, n.obj.x, n.obj.y))
+	}
+	return sb.String()
+}
+
+// around 相邻范围内的其他对象
+// 同时沿x, y两条链表遍历, 先遍历完的那条轴决定候选集
+func (m *CrossListManager[T, P]) around(o *crossObj[T, P]) []*crossObj[T, P] {
+	wx := crossWindow[T, P]{left: o.xNode.prev, right: o.xNode.next, center: o.x, r: m.viewRange, pos: m.xList.pos}
+	wy := crossWindow[T, P]{left: o.yNode.prev, right: o.yNode.next, center: o.y, r: m.viewRange, pos: m.yList.pos}
+	var xs, ys []*crossObj[T, P]
+	for {
+		ox := wx.next()
+		if ox == nil {
+			return m.filterAround(xs, func(other *crossObj[T, P]) bool { return abs(other.y-o.y) <= m.viewRange })
+		}
+		xs = append(xs, ox)
+
+		oy := wy.next()
+		if oy == nil {
+			return m.filterAround(ys, func(other *crossObj[T, P]) bool { return abs(other.x-o.x) <= m.viewRange })
+		}
+		ys = append(ys, oy)
+	}
+}
+
+func (m *CrossListManager[T, P]) filterAround(objs []*crossObj[T, P], f func(other *crossObj[T, P]) bool) []*crossObj[T, P] {
+	n := 0
+	for _, other := range objs {
+		if f(other) {
+			objs[n] = other
+			n++
+		}
+	}
+	return objs[:n]
+}
+
+// visible 相邻的前提下, o在(x,y)时和other是否需要通知
+// 规则和九宫格一致: 行动人是观察者时通知所有人, 否则只通知观察者, 再按可见半径和层过滤
+func (m *CrossListManager[T, P]) visible(o *crossObj[T, P], x, y P, other *crossObj[T, P]) bool {
+	isObserver := o.ot.IsObserver()
+	if !isObserver && !other.ot.IsObserver() {
+		return false
+	}
+	if m.ranged == 0 && m.masked == 0 {
+		return true
+	}
+	d := distanceSq(x, y, other.x, other.y)
+	return (isObserver && inRadius(d, o.viewRadius) && o.sight&other.layer != 0) ||
+		(other.ot.IsObserver() && inRadius(d, other.viewRadius) && other.sight&o.layer != 0)
+}
+
+// sees observer能否看到target: target在observer的可见半径内并且在observer能看到的层
+func (m *CrossListManager[T, P]) sees(observer, target *crossObj[T, P]) bool {
+	return inRadius(distanceSq(observer.x, observer.y, target.x, target.y), observer.viewRadius) &&
+		observer.sight&target.layer != 0
+}
+
+// masked 是否设置了层
+func (o *crossObj[T, P]) masked() bool {
+	return o.layer != AllLayers || o.sight != AllLayers
+}
@@ -0,0 +1,266 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type crossTestObj struct {
+	x, y int
+	ot   ObjType
+}
+
+// crossExpect 暴力计算id在(x,y)时需要通知的对象
+func crossExpect(objs map[int]*crossTestObj, id int, x, y int, ot ObjType, viewRange int) map[int]struct{} {
+	ret := map[int]struct{}{}
+	for other, o := range objs {
+		if other == id {
+			continue
+		}
+		if abs(o.x-x) > viewRange || abs(o.y-y) > viewRange {
+			continue
+		}
+		if !ot.IsObserver() && !o.ot.IsObserver() {
+			continue
+		}
+		ret[other] = struct{}{}
+	}
+	return ret
+}
+
+func TestCrossList_Events(t *testing.T) {
+	const (
+		w, h      = 200, 200
+		viewRange = 15
+	)
+	m, err := NewCrossListManager[int](viewRange)
+	require.Nil(t, err)
+
+	_, err = NewCrossListManager[int](0)
+	require.NotNil(t, err)
+
+	var (
+		objs = map[int]*crossTestObj{}
+		ots  = []ObjType{Trigger, Observer, TriggerAndObserver}
+	)
+	for id := 0; id < 100; id++ {
+		o := &crossTestObj{rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))]}
+		expect := crossExpect(objs, id, o.x, o.y, o.ot, viewRange)
+		objs[id] = o
+		require.True(t, m.Enter(id, o.x, o.y, o.ot, func(event EventType, other int) {
+			require.Equal(t, EnterView, event)
+			_, ok := expect[other]
+			require.True(t, ok, other)
+			delete(expect, other)
+		}))
+		require.Len(t, expect, 0)
+	}
+	require.False(t, m.Enter(0, 0, 0, Trigger, nil))
+
+	for i := 0; i < 2000; i++ {
+		id := rand.Intn(len(objs))
+		o := objs[id]
+		toX, toY := o.x-20+rand.Intn(40), o.y-20+rand.Intn(40)
+		before := crossExpect(objs, id, o.x, o.y, o.ot, viewRange)
+		after := crossExpect(objs, id, toX, toY, o.ot, viewRange)
+		var enter, leave, update []int
+		for other := range after {
+			if _, ok := before[other]; !ok {
+				enter = append(enter, other)
+			} else if o.ot.IsTrigger() && objs[other].ot.IsObserver() {
+				update = append(update, other)
+			}
+		}
+		for other := range before {
+			if _, ok := after[other]; !ok {
+				leave = append(leave, other)
+			}
+		}
+
+		var gotEnter, gotLeave, gotUpdate []int
+		m.Move(id, toX, toY, func(event EventType, other int) {
+			switch event {
+			case EnterView:
+				gotEnter = append(gotEnter, other)
+			case LeaveView:
+				gotLeave = append(gotLeave, other)
+			case UpdateView:
+				gotUpdate = append(gotUpdate, other)
+			}
+		})
+		o.x, o.y = toX, toY
+		require.ElementsMatch(t, enter, gotEnter, fmt.Sprint(i))
+		require.ElementsMatch(t, leave, gotLeave, fmt.Sprint(i))
+		require.ElementsMatch(t, update, gotUpdate, fmt.Sprint(i))
+	}
+
+	for id, o := range objs {
+		expect := crossExpect(objs, id, o.x, o.y, o.ot, viewRange)
+		delete(objs, id)
+		require.True(t, m.Leave(id, func(event EventType, other int) {
+			require.Equal(t, LeaveView, event)
+			_, ok := expect[other]
+			require.True(t, ok, other)
+			delete(expect, other)
+		}))
+		require.Len(t, expect, 0)
+	}
+	require.Equal(t, "", m.String())
+}
+
+func TestCrossList_ViewRadius(t *testing.T) {
+	var m Manager[int, int]
+	m, err := NewCrossListManager[int](10)
+	require.Nil(t, err)
+
+	shouldNotCall := func(event EventType, observer int) {
+		require.Fail(t, "should not call")
+	}
+
+	m.Enter(1, 50, 50, TriggerAndObserver, nil, WithViewRadius(5))
+	_shouldCall := testSet{1: {}}
+	m.Enter(2, 53, 50, Trigger, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+	m.Enter(3, 58, 50, Trigger, shouldNotCall)
+
+	_shouldCall = testSet{1: {}}
+	m.Move(3, 53, 53, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	m.Clear()
+	m.Enter(1, 50, 50, TriggerAndObserver, shouldNotCall)
+
+	// 不支持对象监听, 不能悄悄忽略
+	require.False(t, m.Enter(4, 50, 50, Trigger, nil, WithHandler[int, int](func(*Event[int, int]) {})))
+	require.False(t, m.Leave(4, nil))
+}
+
+func TestCrossList_Mask(t *testing.T) {
+	m, err := NewCrossListManager[int](10)
+	require.Nil(t, err)
+	shouldNotCall := func(event EventType, other int) {
+		require.Fail(t, "should not call", "%v %v", event, other)
+	}
+
+	// 1只能看到层1, 2在层2, 互相不可见
+	m.Enter(1, 50, 50, TriggerAndObserver, nil, WithMask(1, 1))
+	m.Enter(2, 53, 50, Trigger, shouldNotCall, WithMask(2, AllLayers))
+	m.Move(2, 54, 50, shouldNotCall)
+
+	// 3在层1, 能看到层2: 1看到3, 3看到2
+	_shouldCall := testSet{1: {}, 2: {}}
+	m.Enter(3, 52, 50, TriggerAndObserver, _shouldCall.callFunc(t), WithMask(1, 2))
+	_shouldCall.shouldEmpty(t)
+
+	// 2移动, 只有3收到UpdateView
+	_shouldCall = testSet{3: {}}
+	m.Move(2, 53, 51, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	_shouldCall = testSet{1: {}, 2: {}}
+	require.True(t, m.Leave(3, _shouldCall.callFunc(t)))
+	_shouldCall.shouldEmpty(t)
+	require.True(t, m.Leave(1, shouldNotCall))
+	require.True(t, m.Leave(2, nil))
+	require.Equal(t, 0, m.masked)
+
+	// 最后一个设置了层的对象离开后不再过滤
+	m.Enter(1, 50, 50, TriggerAndObserver, nil)
+	_shouldCall = testSet{1: {}}
+	m.Enter(2, 53, 50, Trigger, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+}
+
+func TestCrossList_ViewRadiusReceiver(t *testing.T) {
+	m, err := NewCrossListManager[int](10)
+	require.Nil(t, err)
+	shouldNotCall := func(event EventType, other int) {
+		require.Fail(t, "should not call", "%v %v", event, other)
+	}
+
+	// 最后一个有可见半径的对象离开时, 不能通知没有看到过的对象
+	m.Enter(1, 0, 0, Observer, shouldNotCall, WithViewRadius(5))
+	m.Enter(2, 8, 0, Trigger, shouldNotCall)
+	m.Leave(1, shouldNotCall)
+
+	// 3移动后还在自己的半径内能看到1, 但是不在1的半径内, 不能通知1 UpdateView
+	m.Clear()
+	m.Enter(1, 50, 50, Observer, nil, WithViewRadius(5))
+	m.Enter(3, 59, 50, TriggerAndObserver, nil, WithViewRadius(20))
+	m.Move(3, 58, 51, shouldNotCall)
+}
+
+// 所有对象的可见半径都小于格子大小时, 两种实现只按距离过滤, 事件完全一致
+func TestCrossList_SameAsGrid(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	grid, err := NewAOIManager[int](w, h, 10, 10)
+	require.Nil(t, err)
+	cross, err := NewCrossListManager[int](10)
+	require.Nil(t, err)
+
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	opts := func() []EnterOption {
+		return []EnterOption{WithViewRadius(float64(1 + rand.Intn(9))), WithMask(uint64(1+rand.Intn(3)), uint64(1+rand.Intn(3)))}
+	}
+	for id := 0; id < num; id++ {
+		x, y, ot := rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))]
+		opt := opts()
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		require.True(t, grid.Enter(id, x, y, ot, r1.callFunc(), opt...))
+		require.True(t, cross.Enter(id, x, y, ot, r2.callFunc(), opt...))
+		r1.requireEqual(t, r2, "enter")
+	}
+	for i := 0; i < 5000; i++ {
+		id := rand.Intn(num)
+		o := grid.objs[id]
+		x, y := rand.Intn(w), rand.Intn(h)
+		if i%2 == 0 {
+			x, y = max(0, min(w-1, o.x-15+rand.Intn(30))), max(0, min(h-1, o.y-15+rand.Intn(30)))
+		}
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		if i%10 == 0 {
+			ot, opt := o.ot, opts()
+			grid.Leave(id, r1.callFunc())
+			cross.Leave(id, r2.callFunc())
+			r1.requireEqual(t, r2, fmt.Sprint("leave ", i))
+			r1, r2 = eventRecorder{}, eventRecorder{}
+			grid.Enter(id, x, y, ot, r1.callFunc(), opt...)
+			cross.Enter(id, x, y, ot, r2.callFunc(), opt...)
+			r1.requireEqual(t, r2, fmt.Sprint("enter ", i))
+			continue
+		}
+		grid.Move(id, x, y, r1.callFunc())
+		cross.Move(id, x, y, r2.callFunc())
+		r1.requireEqual(t, r2, fmt.Sprint("move ", i))
+	}
+}
+
+func BenchmarkCrossList_Move(b *testing.B) {
+	const (
+		w   = 1000
+		h   = 1000
+		obj = 10000
+	)
+	m, _ := NewCrossListManager[int](10)
+	pos := make([][2]int, obj)
+	for i := 0; i < obj; i++ {
+		pos[i] = [2]int{rand.Int() % w, rand.Int() % h}
+		m.Enter(i, pos[i][0], pos[i][1], TriggerAndObserver, nil)
+	}
+	cb := func(event EventType, other int) {}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p := &pos[i%obj]
+		p[0], p[1] = p[0]-20+rand.Intn(40), p[1]-20+rand.Intn(40)
+		b.StartTimer()
+		_ = m.Move(i%obj, p[0], p[1], cb)
+	}
+}
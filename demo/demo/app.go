@@ -46,6 +46,10 @@ func runApp(g *game) error {
 				objGrid[o.id] = dot
 			}
 		}
+		seeList := map[int]struct{}{}
+		for _, id := range g.a.VisibleTo(g.currentPlayer.id) {
+			seeList[id] = struct{}{}
+		}
 		for _, o := range g.objs {
 			dot := objGrid[o.id]
 			if dot == nil {
@@ -58,7 +62,7 @@ func runApp(g *game) error {
 			if o.id == g.currentPlayer.id {
 				dot.BorderStyle = ui.NewStyle(ui.ColorRed, ui.ColorRed)
 			} else {
-				if _, ok := g.currentPlayer.seeList[o.id]; ok {
+				if _, ok := seeList[o.id]; ok {
 					if o.isPlayer() {
 						dot.BorderStyle = ui.NewStyle(ui.ColorBlue, ui.ColorBlue)
 					} else {
@@ -12,7 +12,6 @@ type obj struct {
 	x, y       int
 	vx, vy     int
 	playerFlag bool
-	seeList    map[int]struct{}
 }
 
 func (o *obj) name() string {
@@ -27,25 +26,18 @@ func (o *obj) setPos(x, y int) {
 func (o *obj) setVelocity(x, y int) {
 	o.vx, o.vy = x, y
 }
-func (o *obj) enterView(id int) {
-	o.seeList[id] = struct{}{}
-}
-
-func (o *obj) leaveView(id int) {
-	delete(o.seeList, id)
-}
 
 type game struct {
 	objs          map[int]*obj
 	currentPlayer *obj
 	mapW, mapH    int
 	tickCount     int
-	a             *aoi.AOIManager[int]
+	a             *aoi.AOIManager[int, int]
 	pause         bool
 }
 
 func newGame(npcNum int, mapW, mapH, w, h int) *game {
-	a, err := aoi.NewAOIManager[int](mapW, mapH, w, h)
+	a, err := aoi.NewAOIManager[int](mapW, mapH, w, h, aoi.WithVisibility())
 	if err != nil {
 		panic(err)
 	}
@@ -58,10 +50,9 @@ func newGame(npcNum int, mapW, mapH, w, h int) *game {
 
 	for i := 0; i < npcNum; i++ {
 		o := &obj{
-			id:      1000 + i,
-			x:       rand.Int() % g.mapW,
-			y:       rand.Int() % g.mapH,
-			seeList: map[int]struct{}{},
+			id: 1000 + i,
+			x:  rand.Int() % g.mapW,
+			y:  rand.Int() % g.mapH,
 		}
 		g.objs[o.id] = o
 		g.a.Enter(o.id, o.x, o.y, aoi.Trigger, nil)
@@ -76,13 +67,10 @@ func (g *game) choosePlayer(i int) {
 			id:         i,
 			x:          rand.Int() % g.mapW,
 			y:          rand.Int() % g.mapH,
-			seeList:    map[int]struct{}{},
 			playerFlag: true,
 		}
 		g.objs[i] = o
-		g.a.Enter(i, o.x, o.y, aoi.TriggerAndObserver, func(_ aoi.EventType, other int) {
-			o.seeList[other] = struct{}{}
-		})
+		g.a.Enter(i, o.x, o.y, aoi.TriggerAndObserver, nil)
 	}
 	if oldPlayer := g.currentPlayer; oldPlayer != nil {
 		oldPlayer.setVelocity(0, 0)
@@ -112,18 +100,12 @@ func (g *game) tick() {
 			o.y = 0
 		}
 	}
+	// 所有对象移动完再通知视野的变化
+	ops := make([]aoi.MoveOp[int, int], 0, len(g.objs))
 	for _, o := range g.objs {
-		g.a.Move(o.id, o.x, o.y, func(event aoi.EventType, other int) {
-			if event == aoi.EnterView {
-				o.enterView(other)
-				g.objs[other].enterView(o.id)
-			} else if event == aoi.LeaveView {
-				o.leaveView(other)
-				g.objs[other].leaveView(o.id)
-			} else if event == aoi.UpdateView {
-			}
-		})
+		ops = append(ops, aoi.MoveOp[int, int]{ID: o.id, X: o.x, Y: o.y})
 	}
+	g.a.MoveMany(ops, nil)
 
 	g.tickCount++
 	if g.tickCount%10 == 0 {
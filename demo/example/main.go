@@ -19,7 +19,7 @@ const (
 func main() {
 	rand.Seed(time.Now().Unix())
 	// init aoi
-	a, err := aoi.NewAOIManagerFrom[int](0, 0, w, h, gridW, gridH)
+	a, err := aoi.NewAOIManagerFrom[int](0, 0, w, h, gridW, gridH, aoi.WithVisibility())
 	if err != nil {
 		panic(err)
 	}
@@ -35,45 +35,37 @@ func main() {
 	}
 
 	var (
-		seeList    = map[int]struct{}{}
 		fromGridID = 0
 		toGridID   = 0
 	)
 
 	// player enter
-	a.Enter(playerID, w/3, h/2, aoi.TriggerAndObserver, func(_ aoi.EventType, other int) {
-		seeList[other] = struct{}{}
-	})
+	a.Enter(playerID, w/3, h/2, aoi.TriggerAndObserver, nil)
 	fromGridID = a.ObjGrid(playerID).ID()
 	toGridID = a.ObjGrid(playerID).ID()
 
-	fmt.Println("player enter", fromGridID, "seeList:", seeList)
+	fmt.Println("player enter", fromGridID, "seeList:", a.VisibleTo(playerID))
 
 	for i := 0; i < 10; i++ {
 		// player move
 		randX, randY := rand.Int()%w, rand.Int()%h
-		a.Move(playerID, randX, randY, func(event aoi.EventType, other int) {
-			if event == aoi.EnterView { // npc enters player's view
-				seeList[other] = struct{}{}
-			} else if event == aoi.LeaveView { // npc leaves player's view
-				delete(seeList, other)
-			}
-		})
+		a.Move(playerID, randX, randY, nil)
 		toGridID = a.ObjGrid(playerID).ID()
-		fmt.Printf("player move %d->%d. seeList:%v\n", fromGridID, toGridID, seeList)
+		fmt.Printf("player move %d->%d. seeList:%v\n", fromGridID, toGridID, a.VisibleTo(playerID))
 		fromGridID = toGridID
 
 		time.Sleep(time.Second)
 	}
 
 	// player leave
-	a.Leave(playerID, func(_ aoi.EventType, other int) {
-		delete(seeList, other)
-	})
-	fmt.Println("player leave. seeList:", seeList)
+	a.Leave(playerID, nil)
+	fmt.Println("player leave. seeList:", a.VisibleTo(playerID))
 
-	if len(seeList) != 0 {
-		panic("leave failed")
+	// 没有人能看到npc了
+	for i := playerID + 1; i <= 100; i++ {
+		if len(a.VisibleBy(i)) != 0 {
+			panic("leave failed")
+		}
 	}
 
 }
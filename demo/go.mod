@@ -1,6 +1,6 @@
 module github.com/byebyebruce/aoi/demo
 
-go 1.19
+go 1.23
 
 replace github.com/byebyebruce/aoi => ../
 
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/byebyebruce/aoi"
+)
+
+// 回放SetRecorder记录的操作日志, 检查事件是否一致并输出格子的状态
+// 对象id是int, 坐标默认是int, 加-float用float64
+//
+//	go run ./replay -log aoi.log
+//	go run ./replay -log aoi.log -step 100
+func main() {
+	var (
+		logFile = flag.String("log", "", "操作日志文件")
+		step    = flag.Int("step", -1, "执行到第几条日志之前, -1执行全部")
+		float   = flag.Bool("float", false, "坐标是float64")
+	)
+	flag.Parse()
+
+	f, err := os.Open(*logFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if *float {
+		err = replay[float64](f, *step)
+	} else {
+		err = replay[int](f, *step)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func replay[P aoi.Coord](r io.Reader, step int) error {
+	p, err := aoi.NewReplayer[int, P](r)
+	if err != nil {
+		return err
+	}
+	if step < 0 {
+		step = p.Len()
+	}
+	err = p.StepTo(step)
+	fmt.Print(p.Dump())
+	return err
+}
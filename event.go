@@ -0,0 +1,101 @@
+package aoi
+
+/*
+结构化事件
+
+EventCallback只有事件类型和另一方的id, 需要坐标和类型时还要回头查自己的数据。
+SetEventHandler设置的EventHandler会收到完整的Event, 包含双方的id, 坐标, 类型和行动人前后所在的格子,
+可以直接用来组装同步消息。
+
+EventHandler和每次调用传入的EventCallback收到的事件完全一致, 两者同时存在时先回调EventHandler。
+
+MoveMany, SetMask, SetVisibleFunc, UpdateVisible, Resize和Regrid没有单一的行动人,
+从观察者的视角通知, EventHandler和这些方法的参数h收到同样的事件, ObserverView为true:
+Receiver是观察者, Trigger是进出视野或者移动的对象, 不一定是行动人。
+Listener收到的事件也都是观察者视角的。
+*/
+
+// NoGrid 没有格子, Enter时的FromGrid和Leave时的ToGrid
+const NoGrid = -1
+
+// Event aoi事件
+type Event[T ObjID, P Coord] struct {
+	Type EventType // 事件类型
+
+	Trigger     T       // 行动人
+	TriggerType ObjType // 行动人类型
+	// 行动人坐标, Move时是移动后的坐标, Leave时是离开前的坐标
+	TriggerX, TriggerY, TriggerZ P
+
+	Receiver     T       // 被通知的另一方
+	ReceiverType ObjType // 另一方类型
+	// 另一方坐标
+	ReceiverX, ReceiverY, ReceiverZ P
+
+	FromGrid int // 行动人之前所在的格子, Enter时为NoGrid
+	ToGrid   int // 行动人现在所在的格子, Leave时为NoGrid
+
+	Teleport bool // Teleport产生的事件, 客户端可以播放瞬移效果而不是插值移动
+
+	// ObserverView 观察者视角的事件, Receiver是观察者, Trigger是被看到的对象, 不一定是行动人
+	ObserverView bool
+}
+
+// EventHandler 结构化事件回调
+// ***注意***
+// e 只在回调期间有效, 需要保存时复制一份
+type EventHandler[T ObjID, P Coord] func(e *Event[T, P])
+
+// SetEventHandler 设置结构化事件回调, nil取消
+// 也会收到MoveMany, SetMask等观察者视角的事件, 见ObserverView
+func (m *AOIManager[ObjID, P]) SetEventHandler(h EventHandler[ObjID, P]) {
+	m.handler = h
+}
+
+// withHandler 观察者视角的事件先回调handler, 再回调h
+func (m *AOIManager[ObjID, P]) withHandler(h EventHandler[ObjID, P]) EventHandler[ObjID, P] {
+	if m.handler == nil {
+		return h
+	}
+	if h == nil {
+		return m.handler
+	}
+	handler := m.handler
+	return func(e *Event[ObjID, P]) {
+		handler(e)
+		h(e)
+	}
+}
+
+// eventCallback 把handler和cb合成一个EventCallback
+// 没有handler时直接返回cb
+func (m *AOIManager[ObjID, P]) eventCallback(id ObjID, o *obj[ObjID, P], fromGrid, toGrid int, teleport bool, cb EventCallback[ObjID]) EventCallback[ObjID] {
+	if m.handler == nil {
+		return cb
+	}
+	return m.wrapCallback(id, o, fromGrid, toGrid, teleport, cb)
+}
+
+// wrapCallback 先回调handler, 再回调cb
+func (m *AOIManager[ObjID, P]) wrapCallback(id ObjID, o *obj[ObjID, P], fromGrid, toGrid int, teleport bool, cb EventCallback[ObjID]) EventCallback[ObjID] {
+	e := &Event[ObjID, P]{
+		Trigger:     id,
+		TriggerType: o.ot,
+		TriggerX:    o.x, TriggerY: o.y, TriggerZ: o.z,
+		FromGrid: fromGrid,
+		ToGrid:   toGrid,
+		Teleport: teleport,
+	}
+	return func(event EventType, other ObjID) {
+		t := m.objs[other]
+		e.Type = event
+		e.Receiver, e.ReceiverType = other, t.ot
+		e.ReceiverX, e.ReceiverY, e.ReceiverZ = t.x, t.y, t.z
+		if m.handler != nil {
+			m.handler(e)
+		}
+		if cb != nil {
+			cb(event, other)
+		}
+	}
+}
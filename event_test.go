@@ -0,0 +1,129 @@
+package aoi
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_EventHandler(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	var events []Event[int, int]
+	a.SetEventHandler(func(e *Event[int, int]) {
+		events = append(events, *e)
+	})
+
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	require.Len(t, events, 0)
+	a.Enter(2, 25, 15, Trigger, nil)
+	require.Equal(t, []Event[int, int]{{
+		Type:    EnterView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 25, TriggerY: 15,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: NoGrid, ToGrid: 12,
+	}}, events)
+
+	events = nil
+	a.Move(2, 25, 26, nil)
+	require.Equal(t, []Event[int, int]{{
+		Type:    UpdateView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 25, TriggerY: 26,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: 12, ToGrid: 22,
+	}}, events)
+
+	events = nil
+	a.Leave(2, nil)
+	require.Equal(t, []Event[int, int]{{
+		Type:    LeaveView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 25, TriggerY: 26,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: 22, ToGrid: NoGrid,
+	}}, events)
+
+	// 取消后不再回调
+	events = nil
+	a.SetEventHandler(nil)
+	a.Enter(2, 25, 15, Trigger, nil)
+	require.Len(t, events, 0)
+}
+
+func TestAOI_EventHandlerObserverView(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	var events, hEvents []Event[int, int]
+	a.SetEventHandler(func(e *Event[int, int]) {
+		events = append(events, *e)
+	})
+	h := func(e *Event[int, int]) {
+		hEvents = append(hEvents, *e)
+	}
+
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 25, 15, Observer, nil)
+	a.Enter(3, 85, 85, Trigger, nil)
+
+	// MoveMany的事件也会回调EventHandler, 和h收到的一样
+	events = nil
+	require.Equal(t, 1, a.MoveMany([]MoveOp[int, int]{{3, 26, 16}}, h))
+	require.Len(t, events, 2)
+	require.ElementsMatch(t, events, hEvents)
+	for _, e := range events {
+		require.Equal(t, EnterView, e.Type)
+		require.Equal(t, 3, e.Trigger)
+		require.True(t, e.ObserverView)
+	}
+	require.ElementsMatch(t, []int{1, 2}, []int{events[0].Receiver, events[1].Receiver})
+
+	// 1看不到3所在的层, Receiver是观察者1, Trigger是被看到的3
+	events = nil
+	require.True(t, a.SetMask(3, 2, AllLayers, nil))
+	require.Len(t, events, 0)
+	require.True(t, a.SetMask(1, AllLayers, 1, nil))
+	require.Equal(t, []Event[int, int]{{
+		Type:    LeaveView,
+		Trigger: 3, TriggerType: Trigger, TriggerX: 26, TriggerY: 16,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: 12, ToGrid: 12,
+		ObserverView: true,
+	}}, events)
+
+	// Enter, Move和Leave的事件不是观察者视角的
+	events = nil
+	a.Move(3, 27, 17, nil)
+	require.NotEmpty(t, events)
+	for _, e := range events {
+		require.False(t, e.ObserverView)
+	}
+}
+
+func TestAOI_EventHandlerSameAsCallback(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 100
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10)
+	require.Nil(t, err)
+
+	got := eventRecorder{}
+	a.SetEventHandler(func(e *Event[int, int]) {
+		got[e.Type] = append(got[e.Type], e.Receiver)
+	})
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		r := eventRecorder{}
+		got = eventRecorder{}
+		a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], r.callFunc(), WithViewRadius(float64(rand.Intn(20))))
+		r.requireEqual(t, got, "enter")
+	}
+	for i := 0; i < 1000; i++ {
+		r := eventRecorder{}
+		got = eventRecorder{}
+		a.Move(rand.Intn(num), rand.Intn(w), rand.Intn(h), r.callFunc())
+		r.requireEqual(t, got, "move")
+	}
+}
@@ -0,0 +1,85 @@
+package aoi
+
+/*
+可见规则
+
+除了Trigger和Observer, 还可以给对象设置层(WithMask, SetMask)和自定义可见规则(SetVisibleFunc):
+观察者能看到的层(sight)和对象所在的层(layer)有交集, 并且自定义规则返回true, 观察者才能看到对象。
+
+例如
+1. GM对所有人隐身: layer为0
+2. 任务阶段的NPC: layer为任务阶段的位, 处于这个阶段的玩家sight包含这一位
+3. 潜行只对队友可见: 自定义规则里判断是不是同一个队伍
+
+修改层和规则时会从观察者的视角通知EnterView和LeaveView, 和MoveMany一样回调EventHandler, h和Receiver的Listener,
+开启WithVisibility时同时更新可见集合。
+*/
+
+// AllLayers 所有层, 默认所在的层和能看到的层
+const AllLayers = ^uint64(0)
+
+// VisibleFunc 自定义可见规则, observer能否看到target
+type VisibleFunc[T ObjID] func(observer, target T) bool
+
+// WithMask 对象所在的层和能看到的层, 默认都是AllLayers
+func WithMask(layer, sight uint64) EnterOption {
+	return func(o *enterOptions) {
+		o.layer, o.sight = layer, sight
+	}
+}
+
+// SetMask 修改对象所在的层和能看到的层
+// 对象不存在时返回false
+func (m *AOIManager[ObjID, P]) SetMask(id ObjID, layer, sight uint64, h EventHandler[ObjID, P]) bool {
+	o, ok := m.objs[id]
+	if !ok {
+		return false
+	}
+	m.UpdateVisible([]ObjID{id}, func() {
+		if o.masked() {
+			m.masked--
+		}
+		o.layer, o.sight = layer, sight
+		if o.masked() {
+			m.masked++
+		}
+	}, h)
+	return true
+}
+
+// SetVisibleFunc 设置自定义可见规则, nil取消
+func (m *AOIManager[ObjID, P]) SetVisibleFunc(f VisibleFunc[ObjID], h EventHandler[ObjID, P]) {
+	ids := make([]ObjID, 0, len(m.objs))
+	for id := range m.objs {
+		ids = append(ids, id)
+	}
+	m.UpdateVisible(ids, func() {
+		m.visibleFunc = f
+	}, h)
+}
+
+// UpdateVisible 执行update, 重新计算和ids相关的可见关系并通知变化
+// 自定义可见规则依赖的状态变化时(例如组队), 在update里修改状态
+func (m *AOIManager[ObjID, P]) UpdateVisible(ids []ObjID, update func(), h EventHandler[ObjID, P]) {
+	related := make(map[ObjID]int, len(ids))
+	for _, id := range ids {
+		if o, ok := m.objs[id]; ok {
+			related[id] = o.gridID
+		}
+	}
+	h = m.withHandler(h)
+	before := m.seeEdges(related)
+	update()
+	m.emitEdges(before, m.seeEdges(related), nil, nil, h)
+}
+
+// canSee observer能否看到target, 不考虑距离
+func (m *AOIManager[ObjID, P]) canSee(observer, target *obj[ObjID, P]) bool {
+	return observer.sight&target.layer != 0 &&
+		(m.visibleFunc == nil || m.visibleFunc(observer.id, target.id))
+}
+
+// masked 是否设置了层
+func (o *obj[T, P]) masked() bool {
+	return o.layer != AllLayers || o.sight != AllLayers
+}
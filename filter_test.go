@@ -0,0 +1,175 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Mask(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+
+	const questStep = 1 << 1
+	player := &testListener{}
+	a.Enter(1, 15, 15, TriggerAndObserver, nil, WithListener[int, int](player), WithMask(1, 1))
+	// GM对所有人隐身
+	_shouldCall := testSet{1: {}}
+	a.Enter(2, 16, 16, TriggerAndObserver, _shouldCall.callFunc(t), WithMask(0, AllLayers))
+	_shouldCall.shouldEmpty(t)
+	require.Len(t, player.enter, 0)
+	require.ElementsMatch(t, []int{1}, a.VisibleTo(2))
+	require.ElementsMatch(t, []int{}, a.VisibleTo(1))
+
+	// 任务NPC
+	a.Enter(3, 17, 17, Trigger, nil, WithMask(questStep, 0))
+	require.Len(t, player.enter, 0)
+	require.ElementsMatch(t, []int{1, 3}, a.VisibleTo(2))
+
+	// 玩家进入任务阶段
+	var events []Event[int, int]
+	h := func(e *Event[int, int]) {
+		events = append(events, *e)
+	}
+	require.True(t, a.SetMask(1, 1, 1|questStep, h))
+	require.Equal(t, []int{3}, player.enter)
+	require.Len(t, events, 1)
+	require.Equal(t, EnterView, events[0].Type)
+	require.Equal(t, 3, events[0].Trigger)
+	require.Equal(t, 1, events[0].Receiver)
+	require.ElementsMatch(t, []int{3}, a.VisibleTo(1))
+
+	// 移动时也要按层过滤
+	a.Move(3, 18, 18, nil)
+	require.Equal(t, []int{3}, player.update)
+	a.Move(2, 17, 17, nil)
+	require.Len(t, player.update, 1)
+
+	// 离开任务阶段
+	events = nil
+	require.True(t, a.SetMask(1, 1, 1, h))
+	require.Equal(t, []int{3}, player.leave)
+	require.Len(t, events, 1)
+	require.Equal(t, LeaveView, events[0].Type)
+	require.False(t, a.SetMask(4, 0, 0, nil))
+}
+
+func TestAOI_MaskCallback(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	var events []Event[int, int]
+	a.SetEventHandler(func(e *Event[int, int]) {
+		events = append(events, *e)
+	})
+	shouldNotCall := func(event EventType, other int) {
+		require.Fail(t, "should not call", "%v %v", event, other)
+	}
+
+	// 谁都看不到的对象是最后一个设置了层的对象, 离开时也不能通知
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	events = nil
+	a.Enter(2, 16, 16, TriggerAndObserver, shouldNotCall, WithMask(0, 0))
+	a.Leave(2, shouldNotCall)
+	require.Empty(t, events)
+
+	// GM对所有人隐身, 移动时不能把UpdateView通知给看不到自己的玩家
+	r := eventRecorder{}
+	a.Enter(2, 16, 16, TriggerAndObserver, r.callFunc(), WithMask(0, AllLayers))
+	r.requireEqual(t, eventRecorder{EnterView: {1}}, "gm enter")
+	events = nil
+	a.Move(2, 17, 17, shouldNotCall)
+	require.Empty(t, events)
+
+	// 离开时和进入时通知的对象一致
+	r = eventRecorder{}
+	a.Leave(2, r.callFunc())
+	r.requireEqual(t, eventRecorder{LeaveView: {1}}, "gm leave")
+}
+
+func TestAOI_VisibleFunc(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+
+	party := map[int]int{1: 1, 2: 1, 3: 2}
+	stealth := map[int]bool{2: true}
+	visible := func(observer, target int) bool {
+		return !stealth[target] || party[observer] == party[target]
+	}
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 16, 16, TriggerAndObserver, nil)
+	a.Enter(3, 17, 17, TriggerAndObserver, nil)
+	require.ElementsMatch(t, []int{1, 2}, a.VisibleTo(3))
+
+	var events []Event[int, int]
+	h := func(e *Event[int, int]) {
+		events = append(events, *e)
+	}
+	// 2潜行, 只有队友能看到
+	a.SetVisibleFunc(visible, h)
+	require.Len(t, events, 1)
+	require.Equal(t, LeaveView, events[0].Type)
+	require.Equal(t, 2, events[0].Trigger)
+	require.Equal(t, 3, events[0].Receiver)
+	require.ElementsMatch(t, []int{1}, a.VisibleTo(3))
+	require.ElementsMatch(t, []int{1, 3}, a.VisibleTo(2))
+
+	// 3加入队伍
+	events = nil
+	a.UpdateVisible([]int{3}, func() {
+		party[3] = 1
+	}, h)
+	require.Len(t, events, 1)
+	require.Equal(t, EnterView, events[0].Type)
+	require.ElementsMatch(t, []int{1, 2}, a.VisibleTo(3))
+
+	// 取消规则
+	events = nil
+	a.SetVisibleFunc(nil, h)
+	require.Len(t, events, 0)
+}
+
+func TestAOI_FilterRandom(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10, WithVisibility())
+	require.Nil(t, err)
+
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil,
+			WithMask(uint64(rand.Intn(4)), uint64(rand.Intn(4))), WithViewRadius(float64(rand.Intn(20))))
+	}
+	a.SetVisibleFunc(func(observer, target int) bool {
+		return (observer+target)%5 != 0
+	}, nil)
+	for i := 0; i < 2000; i++ {
+		id := rand.Intn(num)
+		switch i % 10 {
+		case 0:
+			a.SetMask(id, uint64(rand.Intn(4)), uint64(rand.Intn(4)), nil)
+		case 1:
+			a.MoveMany([]MoveOp[int, int]{{id, rand.Intn(w), rand.Intn(h)}}, nil)
+		default:
+			a.Move(id, rand.Intn(w), rand.Intn(h), nil)
+		}
+	}
+
+	for id, o := range a.objs {
+		var sees []int
+		a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+			t := a.objs[other]
+			dx, dy := float64(o.x-t.x), float64(o.y-t.y)
+			if other != id && o.ot.IsObserver() && t.ot.IsTrigger() &&
+				(o.viewRadius <= 0 || dx*dx+dy*dy <= o.viewRadius*o.viewRadius) &&
+				o.sight&t.layer != 0 && (id+other)%5 != 0 {
+				sees = append(sees, other)
+			}
+			return true
+		})
+		require.ElementsMatch(t, sees, a.VisibleTo(id), fmt.Sprint(id))
+	}
+}
@@ -17,63 +17,91 @@ func (s set[T]) Foreach(f func(k T) bool) {
 	}
 }
 
+func (s set[T]) slice() []T {
+	ret := make([]T, 0, len(s))
+	for k := range s {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
 // Grid 格子
-type Grid[T ObjID] struct {
-	id                     int        // 格子id
-	row, col               int        // 行列
-	minX, minY, maxX, maxY int        // 格子范围
-	surroundGrids          []*Grid[T] // 包含自己在内的九个格子
-	surroundGridsMap       set[int]   // map用作快速求交集并集
+type Grid[T ObjID, P Coord] struct {
+	id                     int           // 格子id
+	row, col               int           // 行列
+	layer                  int           // 层, 2d时为0
+	minZ, maxZ             P             // 3d的高度范围
+	minX, minY, maxX, maxY P             // 格子范围
+	surroundGrids          []*Grid[T, P] // 包含自己在内的相邻格子
+	surroundGridsMap       set[int]      // map用作快速求交集并集
 
 	observers set[T] // 观察者
 	objs      set[T] // obj
 }
 
-func newGrid[T ObjID](id int, gridMinX, gridMinY, gridMaxX, gridMaxY int, row, col int) *Grid[T] {
-	return &Grid[T]{
+func newGrid[T ObjID, P Coord](id int, gridMinX, gridMinY, gridMaxX, gridMaxY P, row, col int, surroundNum int) *Grid[T, P] {
+	return &Grid[T, P]{
 		id:   id,
 		minX: gridMinX, minY: gridMinY, maxX: gridMaxX, maxY: gridMaxY,
 		objs:             make(map[T]struct{}),
 		col:              col,
 		row:              row,
 		observers:        make(map[T]struct{}),
-		surroundGrids:    make([]*Grid[T], 0, GridNum),
-		surroundGridsMap: make(map[int]struct{}, GridNum),
+		surroundGrids:    make([]*Grid[T, P], 0, surroundNum),
+		surroundGridsMap: make(map[int]struct{}, surroundNum),
 	}
 }
-func (g *Grid[ObjID]) add(obj ObjID, isObserver bool) {
+func (g *Grid[ObjID, P]) add(obj ObjID, isObserver bool) {
 	g.objs[obj] = struct{}{}
 	if isObserver {
 		g.observers[obj] = struct{}{}
 	}
 }
 
-func (g *Grid[ObjID]) del(obj ObjID) {
+func (g *Grid[ObjID, P]) del(obj ObjID) {
 	delete(g.objs, obj)
 	delete(g.observers, obj)
 }
 
-func (g *Grid[ObjID]) clear() {
+func (g *Grid[ObjID, P]) clear() {
 	g.objs = make(map[ObjID]struct{})
 	g.observers = make(map[ObjID]struct{})
 }
 
-func (g *Grid[ObjID]) isSurround(gridID int) bool {
+func (g *Grid[ObjID, P]) isSurround(gridID int) bool {
 	return g.surroundGridsMap.Contains(gridID)
 }
 
-func (g *Grid[ObjID]) addSurroundGrid(other *Grid[ObjID]) {
+func (g *Grid[ObjID, P]) addSurroundGrid(other *Grid[ObjID, P]) {
 	if _, ok := g.surroundGridsMap[other.id]; ok {
 		panic("duplicate grid")
 	}
 	g.surroundGridsMap[other.id] = struct{}{}
+	if len(g.surroundGrids) == cap(g.surroundGrids) {
+		panic(fmt.Sprintf("surround grid num is %d", cap(g.surroundGrids)))
+	}
 	g.surroundGrids = append(g.surroundGrids, other)
-	if len(g.surroundGrids) > GridNum {
-		panic("surround grid num is 9")
+}
+
+// delSurroundGrid 解除和other的相邻关系, 稀疏模式回收格子时用
+func (g *Grid[ObjID, P]) delSurroundGrid(other *Grid[ObjID, P]) {
+	if _, ok := g.surroundGridsMap[other.id]; !ok {
+		return
+	}
+	delete(g.surroundGridsMap, other.id)
+	for i, v := range g.surroundGrids {
+		if v == other {
+			last := len(g.surroundGrids) - 1
+			g.surroundGrids[i] = g.surroundGrids[last]
+			g.surroundGrids[last] = nil
+			g.surroundGrids = g.surroundGrids[:last]
+			break
+		}
 	}
 }
 
-func (g *Grid[ObjID]) invokeEvent(triggerID ObjID, toAll bool, eventType EventType, cb EventCallback[ObjID]) {
+// invokeEvent 通知格子内的对象, filter不为nil时只通知filter返回true的对象
+func (g *Grid[ObjID, P]) invokeEvent(triggerID ObjID, toAll bool, eventType EventType, cb EventCallback[ObjID], filter func(other ObjID) bool) {
 	others := g.observers
 	if toAll {
 		others = g.objs
@@ -82,49 +110,62 @@ func (g *Grid[ObjID]) invokeEvent(triggerID ObjID, toAll bool, eventType EventTy
 		if triggerID == other {
 			continue
 		}
+		if filter != nil && !filter(other) {
+			continue
+		}
 		cb(eventType, other)
 	}
 }
 
 // ID 格子id
-func (g *Grid[ObjID]) ID() int {
+func (g *Grid[ObjID, P]) ID() int {
 	return g.id
 }
 
 // BoundingBox 范围
-func (g *Grid[ObjID]) BoundingBox() (int, int, int, int) {
+func (g *Grid[ObjID, P]) BoundingBox() (P, P, P, P) {
 	return g.minX, g.minY, g.maxX, g.maxY
 }
 
-// RowCol 行列
-func (g *Grid[ObjID]) RowCol() (int, int) {
+// RowCol 行列, 六边形格子时是轴坐标(r, q)
+func (g *Grid[ObjID, P]) RowCol() (int, int) {
 	return g.row, g.col
 }
 
+// Layer 层, 2d时为0
+func (g *Grid[ObjID, P]) Layer() int {
+	return g.layer
+}
+
+// ZRange 3d的高度范围
+func (g *Grid[ObjID, P]) ZRange() (P, P) {
+	return g.minZ, g.maxZ
+}
+
 // Contains 是否包含obj
-func (g *Grid[ObjID]) Contains(obj ObjID) bool {
+func (g *Grid[ObjID, P]) Contains(obj ObjID) bool {
 	_, ok := g.objs[obj]
 	return ok
 }
 
 // ObjIDs 当前格子的所有obj
-func (g *Grid[ObjID]) ObjIDs() set[ObjID] {
+func (g *Grid[ObjID, P]) ObjIDs() set[ObjID] {
 	return g.objs
 }
 
 // ObserverIDs 当前格子的所有观察者
-func (g *Grid[ObjID]) ObserverIDs() set[ObjID] {
+func (g *Grid[ObjID, P]) ObserverIDs() set[ObjID] {
 	return g.observers
 }
 
-// SurroundGrids 九宫格(包括自己)
-func (g *Grid[ObjID]) SurroundGrids() []*Grid[ObjID] {
+// SurroundGrids 相邻格子(包括自己), 默认九宫格
+func (g *Grid[ObjID, P]) SurroundGrids() []*Grid[ObjID, P] {
 	return g.surroundGrids
 }
 
 // ForeachInSurroundGrids 遍历当前格子包含的obj
 // NOTE: 遍历中进制修改grid
-func (g *Grid[ObjID]) ForeachInSurroundGrids(f func(id ObjID) bool) {
+func (g *Grid[ObjID, P]) ForeachInSurroundGrids(f func(id ObjID) bool) {
 	for _, v := range g.surroundGrids {
 		v.objs.Foreach(f)
 	}
@@ -132,12 +173,12 @@ func (g *Grid[ObjID]) ForeachInSurroundGrids(f func(id ObjID) bool) {
 
 // ForeachObserverInSurroundGrids 遍历当前格子包含的obj
 // NOTE: 遍历中进制修改grid
-func (g *Grid[ObjID]) ForeachObserverInSurroundGrids(f func(id ObjID) bool) {
+func (g *Grid[ObjID, P]) ForeachObserverInSurroundGrids(f func(id ObjID) bool) {
 	for _, v := range g.surroundGrids {
 		v.observers.Foreach(f)
 	}
 }
 
-func (g *Grid[ObjID]) String() string {
+func (g *Grid[ObjID, P]) String() string {
 	return fmt.Sprintf("(%d:%d,%d)", g.id, g.row, g.col)
 }
@@ -0,0 +1,110 @@
+package aoi
+
+import "math"
+
+/*
+六边形格子
+
+格子是尖顶朝上的六边形, 存储用odd-r偏移坐标(奇数行向右错开半个格子),
+计算相邻和距离用轴坐标(axial)。
+gridW是同一行相邻两个格子的中心距离, gridH是相邻两行的中心距离,
+正六边形时gridH = gridW * √3 / 2。
+
+	row1    / \ / \ / \
+	       | 3 | 4 | 5 |
+	row0  / \ / \ / \ /
+	     | 0 | 1 | 2 |
+	      \ / \ / \ /
+
+相邻半径为1时周围是7个格子(包括自己), 半径为r时是1+3r(r+1)个,
+不会出现方格九宫格对角线方向看得更远的问题。
+六边形模式下Grid.RowCol返回的是轴坐标(r, q)。
+*/
+
+// WithHex 六边形格子
+func WithHex() Option {
+	return func(o *options) {
+		o.hex = true
+	}
+}
+
+// hexSurroundNum 相邻半径为radius时周围格子的数量(包括自己)
+func hexSurroundNum(radius int) int {
+	return 1 + 3*radius*(radius+1)
+}
+
+// offsetToAxialQ odd-r偏移坐标转轴坐标q, 轴坐标r就是行
+func offsetToAxialQ(row, col int) int {
+	return col - (row-(row&1))/2
+}
+
+// axialToOffsetCol 轴坐标转odd-r偏移坐标的列
+func axialToOffsetCol(q, r int) int {
+	return q + (r-(r&1))/2
+}
+
+// hexRound 小数轴坐标取整到所在的六边形
+func hexRound(q, r float64) (int, int) {
+	s := -q - r
+	rq, rr, rs := math.Round(q), math.Round(r), math.Round(s)
+	dq, dr, ds := math.Abs(rq-q), math.Abs(rr-r), math.Abs(rs-s)
+	if dq > dr && dq > ds {
+		rq = -rr - rs
+	} else if dr > ds {
+		rr = -rq - rs
+	}
+	return int(rq), int(rr)
+}
+
+// hexPosAtRowCol 坐标所在六边形的偏移坐标, 超出范围的返回边界的格子
+func (m *AOIManager[ObjID, P]) hexPosAtRowCol(posX, posY P) (int, int) {
+	// 以格子中心为原点, 格子间距为单位长度
+	u := float64(posX-m.minX)/float64(m.gridW) - 0.5
+	v := float64(posY-m.minY)/float64(m.gridH) - 0.5
+	q, r := hexRound(u-v/2, v)
+	return clampIndex(r, m.row), clampIndex(axialToOffsetCol(q, r), m.col)
+}
+
+// hexBoundingBox 六边形的外接矩形
+func (m *AOIManager[ObjID, P]) hexBoundingBox(row, col int) (P, P, P, P) {
+	centerX := float64(m.minX) + float64(m.gridW)*(float64(col)+0.5+0.5*float64(row&1))
+	centerY := float64(m.minY) + float64(m.gridH)*(float64(row)+0.5)
+	halfW, halfH := float64(m.gridW)/2, float64(m.gridH)*2/3
+	return P(centerX - halfW), P(centerY - halfH), P(centerX + halfW), P(centerY + halfH)
+}
+
+// hexForeachSurround 遍历轴坐标(r, q)周围相邻半径内的格子
+func (m *AOIManager[ObjID, P]) hexForeachSurround(layer, r, q int, f func(idx int)) {
+	for dr := -m.radius; dr <= m.radius; dr++ {
+		_row := r + dr
+		if _row < 0 || _row >= m.row {
+			continue
+		}
+		// 轴坐标距离 max(|dq|, |dr|, |dq+dr|) <= radius
+		minDq, maxDq := -m.radius, m.radius
+		if -dr-m.radius > minDq {
+			minDq = -dr - m.radius
+		}
+		if -dr+m.radius < maxDq {
+			maxDq = -dr + m.radius
+		}
+		for dq := minDq; dq <= maxDq; dq++ {
+			_col := axialToOffsetCol(q+dq, _row)
+			if _col < 0 || _col >= m.col {
+				continue
+			}
+			f(m.layerGridIndex(layer, _row, _col))
+		}
+	}
+}
+
+// clampIndex 把下标限制在[0, n)
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,128 @@
+package aoi
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hexDistance 轴坐标距离
+func hexDistance(r1, q1, r2, q2 int) int {
+	dq, dr := q1-q2, r1-r2
+	d := abs(dq)
+	if abs(dr) > d {
+		d = abs(dr)
+	}
+	if abs(dq+dr) > d {
+		d = abs(dq + dr)
+	}
+	return d
+}
+
+func TestAOI_HexInit(t *testing.T) {
+	a, err := NewAOIManager[int, float64](100, 100*math.Sqrt(3)/2, 10, 10*math.Sqrt(3)/2, WithHex())
+	require.Nil(t, err)
+	require.EqualValues(t, 10, a.col)
+	require.EqualValues(t, 10, a.row)
+
+	for _, g := range a.AllGrids() {
+		r, q := g.RowCol()
+		for _, sg := range g.SurroundGrids() {
+			sr, sq := sg.RowCol()
+			require.LessOrEqual(t, hexDistance(r, q, sr, sq), 1)
+		}
+		if r > 0 && r < a.row-1 && g.id%a.col > 0 && g.id%a.col < a.col-1 {
+			require.Len(t, g.SurroundGrids(), 7)
+		}
+	}
+
+	a2, err := NewAOIManager[int, float64](100, 100, 10, 10, WithHex(), WithRadius(2))
+	require.Nil(t, err)
+	require.Len(t, a2.PosAtGrid(50, 50).SurroundGrids(), 19)
+}
+
+func TestAOI_HexPosAtGrid(t *testing.T) {
+	a, err := NewAOIManager[int, float64](100, 100, 10, 10, WithHex())
+	require.Nil(t, err)
+
+	// 格子中心落在自己的格子里
+	for _, g := range a.AllGrids() {
+		row, col := g.id/a.col, g.id%a.col
+		x := 10 * (float64(col) + 0.5 + 0.5*float64(row&1))
+		y := 10 * (float64(row) + 0.5)
+		require.Same(t, g, a.PosAtGrid(x, y))
+	}
+	// 出界的坐标返回边界的格子
+	require.EqualValues(t, 0, a.PosAtGrid(-100, -100).ID())
+	require.EqualValues(t, a.col*a.row-1, a.PosAtGrid(1000, 1000).ID())
+
+	// 偶数行格子(0,1)的对角(1,2)不相邻
+	g := a.PosAtGrid(15, 5)
+	require.True(t, g.isSurround(a.PosAtGrid(10, 15).ID()))
+	require.True(t, g.isSurround(a.PosAtGrid(20, 15).ID()))
+	require.False(t, g.isSurround(a.PosAtGrid(30, 15).ID()))
+}
+
+func TestAOI_HexMove(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10, WithHex())
+	require.Nil(t, err)
+	sparse, err := NewAOIManager[int](w, h, 10, 10, WithHex(), WithSparse())
+	require.Nil(t, err)
+
+	type pos struct{ x, y int }
+	objs := map[int]pos{}
+	// 暴力计算和id相邻的对象
+	expect := func(id int, x, y int) map[int]struct{} {
+		r, q := a.PosAtGrid(x, y).RowCol()
+		ret := map[int]struct{}{}
+		for other, p := range objs {
+			if other == id {
+				continue
+			}
+			or, oq := a.PosAtGrid(p.x, p.y).RowCol()
+			if hexDistance(r, q, or, oq) <= 1 {
+				ret[other] = struct{}{}
+			}
+		}
+		return ret
+	}
+
+	for id := 0; id < num; id++ {
+		x, y := rand.Intn(w), rand.Intn(h)
+		objs[id] = pos{x, y}
+		a.Enter(id, x, y, TriggerAndObserver, nil)
+		sparse.Enter(id, x, y, TriggerAndObserver, nil)
+	}
+	for i := 0; i < 1000; i++ {
+		id := rand.Intn(num)
+		from := objs[id]
+		x, y := from.x-20+rand.Intn(40), from.y-20+rand.Intn(40)
+		before, after := expect(id, from.x, from.y), expect(id, x, y)
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		a.Move(id, x, y, r1.callFunc())
+		sparse.Move(id, x, y, r2.callFunc())
+		objs[id] = pos{x, y}
+		r1.requireEqual(t, r2, "move")
+
+		var enter, leave, update []int
+		for other := range after {
+			if _, ok := before[other]; ok {
+				update = append(update, other)
+			} else {
+				enter = append(enter, other)
+			}
+		}
+		for other := range before {
+			if _, ok := after[other]; !ok {
+				leave = append(leave, other)
+			}
+		}
+		r1.requireEqual(t, eventRecorder{EnterView: enter, LeaveView: leave, UpdateView: update}, "expect")
+	}
+}
@@ -0,0 +1,46 @@
+package aoi
+
+import "math"
+
+/*
+格子切换的滞后
+
+对象沿着格子边界来回走时, 每次跨过边界都会换格子, 边界另一侧的观察者会反复收到LeaveView和EnterView。
+WithHysteresis设置滞后距离后, Move时对象离开当前格子的距离不超过滞后距离就还留在当前格子,
+走出滞后距离才切换到新的格子。
+
+***注意***
+对象所在的格子(ObjGrid)和坐标所在的格子(PosAtGrid)可能不一样。
+*/
+
+// WithHysteresis 切换格子的滞后距离, 0表示不滞后
+// 一般设置为格子宽高的一小部分, 超过格子宽高时相当于一直不切换到相邻格子
+func WithHysteresis(margin float64) Option {
+	return func(o *options) {
+		o.hysteresis = margin
+	}
+}
+
+// inHysteresis 坐标是否还在格子g向外扩展滞后距离的范围内
+func (m *AOIManager[ObjID, P]) inHysteresis(g *Grid[ObjID, P], posX, posY, posZ P) bool {
+	return outside(posX, g.minX, g.maxX, m.minX, m.maxX, m.wrap) <= m.hysteresis &&
+		outside(posY, g.minY, g.maxY, m.minY, m.maxY, m.wrap) <= m.hysteresis &&
+		outside(posZ, g.minZ, g.maxZ, m.minZ, m.maxZ, false) <= m.hysteresis
+}
+
+// outside 坐标在格子[lo, hi)外面的距离, 在里面时为0
+// 地图边界的格子向外延伸到无穷远, 环形地图按环形距离计算
+func outside[P Coord](pos, lo, hi, min, max P, wrap bool) float64 {
+	if wrap {
+		pos = wrapCoord(pos, min, max)
+	}
+	if (pos >= lo || (!wrap && lo == min)) && (pos < hi || (!wrap && hi == max)) {
+		return 0
+	}
+	d1, d2 := math.Abs(float64(lo)-float64(pos)), math.Abs(float64(pos)-float64(hi))
+	if wrap {
+		size := float64(max - min)
+		d1, d2 = math.Min(d1, size-d1), math.Min(d2, size-d2)
+	}
+	return math.Min(d1, d2)
+}
@@ -0,0 +1,56 @@
+package aoi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Hysteresis(t *testing.T) {
+	_, err := NewAOIManager[int](100, 100, 10, 10, WithHysteresis(-1))
+	require.NotNil(t, err)
+	_, err = NewShardedAOIManager[int](100, 100, 10, 10, 1, WithHysteresis(1))
+	require.NotNil(t, err)
+
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithHysteresis(3))
+	require.Nil(t, err)
+	shouldNotCall := func(event EventType, observer int) {
+		require.Fail(t, "should not call")
+	}
+
+	a.Enter(1, 35, 5, Observer, nil)
+	a.Enter(2, 15, 5, Trigger, shouldNotCall)
+
+	// 在边界附近来回走, 不切换格子
+	a.Move(2, 21, 5, shouldNotCall)
+	require.Equal(t, 1, a.ObjGrid(2).ID())
+	a.Move(2, 19, 5, shouldNotCall)
+	a.Move(2, 23, 7, shouldNotCall)
+	require.Equal(t, 1, a.ObjGrid(2).ID())
+
+	// 走出滞后距离才切换
+	_shouldCall := testSet{1: {}}
+	a.Move(2, 24, 5, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+	require.Equal(t, 2, a.ObjGrid(2).ID())
+	a.Move(2, 17, 5, nil)
+	require.Equal(t, 2, a.ObjGrid(2).ID())
+	_shouldCall = testSet{1: {}}
+	a.Move(2, 16, 5, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+	require.Equal(t, 1, a.ObjGrid(2).ID())
+
+	// 地图边界的格子向外无限延伸
+	a.Move(2, 5, 5, nil)
+	a.Move(2, -100, -100, nil)
+	require.Equal(t, 0, a.ObjGrid(2).ID())
+}
+
+func TestAOI_HysteresisOutside(t *testing.T) {
+	require.EqualValues(t, 0, outside(5, 0, 10, 0, 100, false))
+	require.EqualValues(t, 2, outside(12, 0, 10, 0, 100, false))
+	require.EqualValues(t, 0, outside(-5, 0, 10, 0, 100, false))
+	require.EqualValues(t, 5, outside(-5, 0, 10, 0, 100, true))
+	require.EqualValues(t, 0, outside(105, 0, 10, 0, 100, true))
+	require.EqualValues(t, 3, outside(103, 90, 100, 0, 100, true))
+}
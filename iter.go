@@ -0,0 +1,128 @@
+package aoi
+
+import "iter"
+
+/*
+迭代器
+
+Go 1.23的range-over-func迭代器, 可以直接用for range遍历:
+
+	for id := range m.Neighbors(player) {
+		...
+	}
+
+迭代器直接遍历内部的集合, 不会复制。
+遍历中修改管理器(Enter, Leave, Move等)的结果未定义, 需要修改时先用Snapshot复制一份:
+
+	for id := range aoi.Snapshot(m.Neighbors(player)) {
+		m.Leave(id, nil)
+	}
+*/
+
+// Snapshot 先把seq遍历完复制一份, 再从副本yield, 遍历中可以修改管理器
+func Snapshot[T any](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var s []T
+		for v := range seq {
+			s = append(s, v)
+		}
+		for _, v := range s {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// seqOf 遍历集合的迭代器
+func seqOf[T ObjID](s set[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for k := range s {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// All 格子内的所有对象
+func (g *Grid[ObjID, P]) All() iter.Seq[ObjID] {
+	return seqOf(g.objs)
+}
+
+// Observers 格子内的所有观察者
+func (g *Grid[ObjID, P]) Observers() iter.Seq[ObjID] {
+	return seqOf(g.observers)
+}
+
+// Surround 相邻格子(包括自己)内的所有对象
+func (g *Grid[ObjID, P]) Surround() iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		for _, sg := range g.surroundGrids {
+			for id := range sg.objs {
+				if !yield(id) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// All 所有对象
+func (m *AOIManager[ObjID, P]) All() iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		for id := range m.objs {
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// Neighbors id所在格子的相邻格子内的其他对象, 不包括自己, 不考虑可见半径和可见规则
+// 对象不存在时什么都不遍历
+func (m *AOIManager[ObjID, P]) Neighbors(id ObjID) iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		o, ok := m.objs[id]
+		if !ok {
+			return
+		}
+		for other := range m.grid(o.gridID).Surround() {
+			if other != id && !yield(other) {
+				return
+			}
+		}
+	}
+}
+
+// Sees id能看到的对象, 同VisibleTo, 需要开启WithVisibility
+func (m *AOIManager[ObjID, P]) Sees(id ObjID) iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		if o, ok := m.objs[id]; ok {
+			seqOf(o.sees)(yield)
+		}
+	}
+}
+
+// SeenBy 能看到id的观察者, 同VisibleBy, 需要开启WithVisibility
+func (m *AOIManager[ObjID, P]) SeenBy(id ObjID) iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		if o, ok := m.objs[id]; ok {
+			seqOf(o.seenBy)(yield)
+		}
+	}
+}
+
+// InRect 坐标在矩形内的对象, 同QueryRect
+func (m *AOIManager[ObjID, P]) InRect(minX, minY, maxX, maxY P, ot ObjType) iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		m.foreachInRect(minX, minY, maxX, maxY, ot, yield)
+	}
+}
+
+// InCircle 坐标在圆内的对象, 同QueryCircle
+func (m *AOIManager[ObjID, P]) InCircle(x, y P, r float64, ot ObjType) iter.Seq[ObjID] {
+	return func(yield func(ObjID) bool) {
+		m.foreachInCircle(x, y, r, ot, yield)
+	}
+}
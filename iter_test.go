@@ -0,0 +1,50 @@
+package aoi
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Iter(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 16, 16, Observer, nil)
+	a.Enter(3, 25, 25, Trigger, nil)
+	a.Enter(4, 80, 80, Trigger, nil)
+
+	require.ElementsMatch(t, []int{1, 2, 3, 4}, slices.Collect(a.All()))
+	require.ElementsMatch(t, []int{1, 2}, slices.Collect(a.ObjGrid(1).All()))
+	require.ElementsMatch(t, []int{1, 2}, slices.Collect(a.ObjGrid(1).Observers()))
+	require.ElementsMatch(t, []int{1, 2, 3}, slices.Collect(a.ObjGrid(1).Surround()))
+	require.ElementsMatch(t, []int{2, 3}, slices.Collect(a.Neighbors(1)))
+	require.Empty(t, slices.Collect(a.Neighbors(5)))
+	require.ElementsMatch(t, a.VisibleTo(2), slices.Collect(a.Sees(2)))
+	require.ElementsMatch(t, a.VisibleBy(3), slices.Collect(a.SeenBy(3)))
+	require.Empty(t, slices.Collect(a.Sees(5)))
+	require.ElementsMatch(t, a.QueryRect(10, 10, 30, 30, Trigger), slices.Collect(a.InRect(10, 10, 30, 30, Trigger)))
+	require.ElementsMatch(t, a.QueryCircle(15, 15, 20, 0), slices.Collect(a.InCircle(15, 15, 20, 0)))
+
+	// 提前break
+	n := 0
+	for range a.Neighbors(1) {
+		n++
+		break
+	}
+	require.Equal(t, 1, n)
+	n = 0
+	for range Snapshot(a.All()) {
+		n++
+		break
+	}
+	require.Equal(t, 1, n)
+
+	// 遍历中修改
+	for id := range Snapshot(a.Neighbors(1)) {
+		require.True(t, a.Leave(id, nil))
+	}
+	require.ElementsMatch(t, []int{1, 4}, slices.Collect(a.All()))
+	require.Empty(t, slices.Collect(a.Sees(1)))
+}
@@ -0,0 +1,103 @@
+package aoi
+
+/*
+对象事件监听
+
+EventCallback和EventHandler只从行动人的视角回调一次, 另一方的状态要调用方自己维护。
+SetListener给对象设置监听后, 不管是谁的操作, 每个受影响的对象都会收到自己视角的事件:
+
+1. 观察者看到触发者: 行动人是触发者时, 通知周围的观察者, Trigger是行动人, Receiver是观察者
+2. 行动人看到别人: 行动人是观察者时, 通知行动人周围的触发者进入或离开,
+Trigger是进出视野的对象, Receiver是行动人, FromGrid和ToGrid都是Trigger所在的格子
+3. UpdateView只通知观察者, 行动人不会收到别人的UpdateView
+
+能否看到只按观察者自己的可见半径和可见规则判断, 和cb的"任意一方能看到"不同, 事件的ObserverView为true。
+
+收到事件的对象一定是Receiver, 不需要再判断自己是哪一方。
+只关心一个回调函数时可以用SetHandler, EventHandler也实现了Listener。
+*/
+
+// Listener 对象事件监听
+// ***注意***
+// e 只在回调期间有效, 需要保存时复制一份
+type Listener[T ObjID, P Coord] interface {
+	// OnEnter Trigger进入视野
+	OnEnter(e *Event[T, P])
+	// OnLeave Trigger离开视野
+	OnLeave(e *Event[T, P])
+	// OnUpdate 视野内的Trigger移动
+	OnUpdate(e *Event[T, P])
+}
+
+// OnEnter 实现Listener
+func (h EventHandler[T, P]) OnEnter(e *Event[T, P]) { h(e) }
+
+// OnLeave 实现Listener
+func (h EventHandler[T, P]) OnLeave(e *Event[T, P]) { h(e) }
+
+// OnUpdate 实现Listener
+func (h EventHandler[T, P]) OnUpdate(e *Event[T, P]) { h(e) }
+
+// WithListener 进入时设置对象的事件监听, 可以收到自己进入时的EnterView
+// 类型参数需要和AOIManager一致, 否则Enter返回false; CrossListManager和ShardedAOIManager不支持, Enter返回false
+func WithListener[T ObjID, P Coord](l Listener[T, P]) EnterOption {
+	return func(o *enterOptions) {
+		if l != nil {
+			o.listener = l
+		}
+	}
+}
+
+// WithHandler 进入时设置对象的事件回调, 同WithListener
+func WithHandler[T ObjID, P Coord](h EventHandler[T, P]) EnterOption {
+	if h == nil {
+		return WithListener[T, P](nil)
+	}
+	return WithListener[T, P](h)
+}
+
+// listenerOf 进入选项里的监听, 类型参数和管理器不一致时返回false
+func listenerOf[T ObjID, P Coord](eo *enterOptions) (Listener[T, P], bool) {
+	if eo.listener == nil {
+		return nil, true
+	}
+	l, ok := eo.listener.(Listener[T, P])
+	return l, ok
+}
+
+// SetListener 设置对象的事件监听, nil取消
+// 对象不存在时返回false
+func (m *AOIManager[ObjID, P]) SetListener(id ObjID, l Listener[ObjID, P]) bool {
+	o, ok := m.objs[id]
+	if !ok {
+		return false
+	}
+	switch {
+	case o.listener == nil && l != nil:
+		m.listened++
+	case o.listener != nil && l == nil:
+		m.listened--
+	}
+	o.listener = l
+	return true
+}
+
+// SetHandler 设置对象的事件回调, nil取消, 同SetListener
+func (m *AOIManager[ObjID, P]) SetHandler(id ObjID, h EventHandler[ObjID, P]) bool {
+	if h == nil {
+		return m.SetListener(id, nil)
+	}
+	return m.SetListener(id, h)
+}
+
+// notify 按事件类型回调监听
+func notify[T ObjID, P Coord](l Listener[T, P], e *Event[T, P]) {
+	switch e.Type {
+	case EnterView:
+		l.OnEnter(e)
+	case LeaveView:
+		l.OnLeave(e)
+	case UpdateView:
+		l.OnUpdate(e)
+	}
+}
@@ -0,0 +1,218 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Handler(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	records := map[int][]Event[int, int]{}
+	handler := func(id int) EventHandler[int, int] {
+		return func(e *Event[int, int]) {
+			require.Equal(t, id, e.Receiver)
+			records[id] = append(records[id], *e)
+		}
+	}
+
+	a.Enter(1, 15, 15, TriggerAndObserver, nil, WithHandler(handler(1)))
+	a.Enter(2, 25, 15, Trigger, nil, WithHandler(handler(2)))
+	a.Enter(3, 15, 25, Observer, nil, WithHandler(handler(3)))
+	require.False(t, a.SetHandler(4, handler(4)))
+
+	// 1看到2, 3看到1和2, 2是触发者看不到任何人, 1看不到只是观察者的3
+	require.Len(t, records[1], 1)
+	require.Equal(t, Event[int, int]{
+		Type:    EnterView,
+		Trigger: 2, TriggerType: Trigger, TriggerX: 25, TriggerY: 15,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 15, ReceiverY: 15,
+		FromGrid: NoGrid, ToGrid: 12,
+		ObserverView: true,
+	}, records[1][0])
+	require.Len(t, records[2], 0)
+	require.Len(t, records[3], 2)
+	require.Contains(t, records[3], Event[int, int]{
+		Type:    EnterView,
+		Trigger: 1, TriggerType: TriggerAndObserver, TriggerX: 15, TriggerY: 15,
+		Receiver: 3, ReceiverType: Observer, ReceiverX: 15, ReceiverY: 25,
+		FromGrid: 11, ToGrid: 11,
+		ObserverView: true,
+	})
+
+	// 2移动, 1和3收到UpdateView
+	records = map[int][]Event[int, int]{}
+	a.Move(2, 26, 16, nil)
+	require.Len(t, records[1], 1)
+	require.Equal(t, UpdateView, records[1][0].Type)
+	require.Len(t, records[3], 1)
+	require.Equal(t, UpdateView, records[3][0].Type)
+
+	// 1离开, 自己收到2离开, 3收到1离开
+	records = map[int][]Event[int, int]{}
+	a.Leave(1, nil)
+	require.Len(t, records[1], 1)
+	require.Equal(t, LeaveView, records[1][0].Type)
+	require.Equal(t, 2, records[1][0].Trigger)
+	require.Len(t, records[3], 1)
+	require.Equal(t, LeaveView, records[3][0].Type)
+	require.Equal(t, 1, records[3][0].Trigger)
+
+	// 取消
+	records = map[int][]Event[int, int]{}
+	require.True(t, a.SetHandler(3, nil))
+	a.Move(2, 25, 15, nil)
+	require.Len(t, records, 0)
+	require.Equal(t, 1, a.listened)
+}
+
+func TestAOI_HandlerSeeList(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 100
+	)
+	for _, sparse := range []bool{false, true} {
+		opts := []Option{WithRadius(2)}
+		if sparse {
+			opts = append(opts, WithSparse())
+		}
+		a, err := NewAOIManager[int](w, h, 10, 10, opts...)
+		require.Nil(t, err)
+
+		seeList := map[int]map[int]struct{}{}
+		handler := func(id int) EventHandler[int, int] {
+			return func(e *Event[int, int]) {
+				switch e.Type {
+				case EnterView:
+					_, ok := seeList[id][e.Trigger]
+					require.False(t, ok)
+					seeList[id][e.Trigger] = struct{}{}
+				case LeaveView:
+					_, ok := seeList[id][e.Trigger]
+					require.True(t, ok)
+					delete(seeList[id], e.Trigger)
+				case UpdateView:
+					_, ok := seeList[id][e.Trigger]
+					require.True(t, ok)
+				}
+			}
+		}
+
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			seeList[id] = map[int]struct{}{}
+			a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil, WithHandler(handler(id)))
+		}
+		for i := 0; i < 2000; i++ {
+			id := rand.Intn(num)
+			if i%10 == 0 {
+				ot := a.objs[id].ot
+				a.Leave(id, nil)
+				require.Len(t, seeList[id], 0)
+				a.Enter(id, rand.Intn(w), rand.Intn(h), ot, nil, WithHandler(handler(id)))
+				continue
+			}
+			a.Move(id, rand.Intn(w), rand.Intn(h), nil)
+		}
+
+		// 每个观察者看到的正好是相邻格子内的触发者
+		for id, o := range a.objs {
+			expect := map[int]struct{}{}
+			if o.ot.IsObserver() {
+				a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+					if other != id && a.objs[other].ot.IsTrigger() {
+						expect[other] = struct{}{}
+					}
+					return true
+				})
+			}
+			require.Equal(t, expect, seeList[id], fmt.Sprint(id))
+		}
+	}
+}
+
+type testListener struct {
+	enter, leave, update []int
+}
+
+func (l *testListener) OnEnter(e *Event[int, int])  { l.enter = append(l.enter, e.Trigger) }
+func (l *testListener) OnLeave(e *Event[int, int])  { l.leave = append(l.leave, e.Trigger) }
+func (l *testListener) OnUpdate(e *Event[int, int]) { l.update = append(l.update, e.Trigger) }
+
+func TestAOI_Listener(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+
+	l1, l2 := &testListener{}, &testListener{}
+	require.False(t, a.SetListener(1, l1))
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	require.True(t, a.SetListener(1, l1))
+	a.Enter(2, 25, 15, TriggerAndObserver, nil, WithListener[int, int](l2))
+	a.Enter(3, 15, 25, Trigger, nil)
+	require.ElementsMatch(t, []int{2, 3}, l1.enter)
+	require.ElementsMatch(t, []int{1, 3}, l2.enter)
+
+	// 别人的移动也会通知
+	a.Move(3, 16, 26, nil)
+	require.Equal(t, []int{3}, l1.update)
+	require.Equal(t, []int{3}, l2.update)
+
+	a.Move(3, 85, 85, nil)
+	require.Equal(t, []int{3}, l1.leave)
+	require.Equal(t, []int{3}, l2.leave)
+
+	a.Move(1, 85, 75, nil)
+	require.Equal(t, []int{3, 2}, l1.leave)
+	require.Equal(t, 3, l1.enter[len(l1.enter)-1])
+	require.Equal(t, []int{3, 1}, l2.leave)
+
+	a.Clear()
+	require.Equal(t, 0, a.listened)
+
+	// 类型参数不一致的监听不能悄悄忽略
+	require.False(t, a.Enter(4, 15, 15, Observer, nil, WithHandler[int, float64](func(*Event[int, float64]) {})))
+	require.False(t, a.Has(4))
+	s := NewSafeAOIManager(a)
+	require.False(t, s.Enter(4, 15, 15, Observer, nil, WithHandler[string, int](func(*Event[string, int]) {})))
+	require.False(t, s.Has(4))
+	require.True(t, s.Enter(4, 15, 15, Observer, nil))
+}
+
+func TestAOI_ListenerViewRadius(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+
+	// 每个方向只看观察者自己的可见半径
+	l1, l2, l3 := &testListener{}, &testListener{}, &testListener{}
+	a.Enter(1, 10, 10, TriggerAndObserver, nil, WithViewRadius(5), WithListener[int, int](l1))
+	a.Enter(2, 18, 10, TriggerAndObserver, nil, WithViewRadius(50), WithListener[int, int](l2))
+	require.Empty(t, l1.enter)
+	require.Equal(t, []int{1}, l2.enter)
+	require.Empty(t, a.VisibleTo(1))
+	require.Equal(t, []int{1}, a.VisibleTo(2))
+
+	// 2进入1的半径, 1收到EnterView而不是UpdateView
+	a.Move(2, 13, 10, nil)
+	require.Equal(t, []int{2}, l1.enter)
+	require.Empty(t, l1.update)
+	require.Empty(t, l2.update)
+	require.Equal(t, []int{2}, a.VisibleTo(1))
+
+	// 只是观察者的3走进自己的半径也能看到2
+	a.Enter(3, 30, 10, Observer, nil, WithViewRadius(5), WithListener[int, int](l3))
+	require.Empty(t, l3.enter)
+	a.Move(3, 16, 10, nil)
+	require.Equal(t, []int{2}, l3.enter)
+	require.Equal(t, []int{2}, a.VisibleTo(3))
+
+	// 2走出1的半径, 还在3的半径内
+	a.Move(2, 19, 10, nil)
+	require.Equal(t, []int{2}, l1.leave)
+	require.Equal(t, []int{2}, l3.update)
+	require.Empty(t, a.VisibleTo(1))
+	require.Equal(t, []int{1}, a.VisibleTo(2))
+}
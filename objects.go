@@ -0,0 +1,69 @@
+package aoi
+
+/*
+对象查询
+
+查询对象是否存在, 坐标, 类型和数量, 不需要调用方自己再维护一份对象数据。
+*/
+
+// Has 对象是否存在
+func (m *AOIManager[ObjID, P]) Has(id ObjID) bool {
+	_, ok := m.objs[id]
+	return ok
+}
+
+// Pos 对象的坐标, 对象不存在时ok为false
+func (m *AOIManager[ObjID, P]) Pos(id ObjID) (x, y P, ok bool) {
+	o, ok := m.objs[id]
+	if !ok {
+		return x, y, false
+	}
+	return o.x, o.y, true
+}
+
+// Type 对象的类型, 对象不存在时ok为false
+func (m *AOIManager[ObjID, P]) Type(id ObjID) (ot ObjType, ok bool) {
+	o, ok := m.objs[id]
+	if !ok {
+		return 0, false
+	}
+	return o.ot, true
+}
+
+// Len 对象总数
+func (m *AOIManager[ObjID, P]) Len() int {
+	return len(m.objs)
+}
+
+// Count 类型和ot有交集的对象数, ot为0时是对象总数
+// 例如Count(Observer)包括Observer和TriggerAndObserver
+func (m *AOIManager[ObjID, P]) Count(ot ObjType) int {
+	if ot == 0 {
+		return len(m.objs)
+	}
+	n := 0
+	for t, c := range m.typeCount {
+		if t&ot != 0 {
+			n += c
+		}
+	}
+	return n
+}
+
+// ForeachObj 遍历所有对象, f返回false时停止, 顺序不固定
+// NOTE: 遍历中禁止Enter, Leave, Move
+func (m *AOIManager[ObjID, P]) ForeachObj(f func(id ObjID, x, y P, ot ObjType) bool) {
+	for id, o := range m.objs {
+		if !f(id, o.x, o.y, o.ot) {
+			break
+		}
+	}
+}
+
+// countType 修改类型为ot的对象数
+func (m *AOIManager[ObjID, P]) countType(ot ObjType, n int) {
+	m.typeCount[ot] += n
+	if m.typeCount[ot] == 0 {
+		delete(m.typeCount, ot)
+	}
+}
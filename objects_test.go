@@ -0,0 +1,67 @@
+package aoi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Objects(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	require.Equal(t, 0, a.Len())
+	require.False(t, a.Has(1))
+	_, _, ok := a.Pos(1)
+	require.False(t, ok)
+	_, ok = a.Type(1)
+	require.False(t, ok)
+
+	a.Enter(1, 15, 25, Trigger, nil)
+	a.Enter(2, 35, 45, Observer, nil)
+	a.Enter(3, 55, 65, TriggerAndObserver, nil)
+	a.Enter(4, 75, 85, TriggerAndObserver, nil)
+	require.True(t, a.Has(1))
+	require.Equal(t, 4, a.Len())
+	x, y, ok := a.Pos(1)
+	require.True(t, ok)
+	require.Equal(t, []int{15, 25}, []int{x, y})
+	a.Move(1, 16, 26, nil)
+	x, y, _ = a.Pos(1)
+	require.Equal(t, []int{16, 26}, []int{x, y})
+	ot, ok := a.Type(2)
+	require.True(t, ok)
+	require.Equal(t, Observer, ot)
+
+	require.Equal(t, 4, a.Count(0))
+	require.Equal(t, 3, a.Count(Trigger))
+	require.Equal(t, 3, a.Count(Observer))
+	require.Equal(t, 4, a.Count(TriggerAndObserver))
+
+	a.SetObjType(3, Observer, nil)
+	require.Equal(t, 2, a.Count(Trigger))
+	a.Leave(4, nil)
+	require.Equal(t, 1, a.Count(Trigger))
+	require.Equal(t, 2, a.Count(Observer))
+	require.False(t, a.Has(4))
+
+	objs := map[int][3]int{}
+	a.ForeachObj(func(id int, x, y int, ot ObjType) bool {
+		objs[id] = [3]int{x, y, int(ot)}
+		return true
+	})
+	require.Equal(t, map[int][3]int{
+		1: {16, 26, int(Trigger)},
+		2: {35, 45, int(Observer)},
+		3: {55, 65, int(Observer)},
+	}, objs)
+	n := 0
+	a.ForeachObj(func(int, int, int, ObjType) bool {
+		n++
+		return false
+	})
+	require.Equal(t, 1, n)
+
+	a.Clear()
+	require.Equal(t, 0, a.Len())
+	require.Equal(t, 0, a.Count(Trigger))
+}
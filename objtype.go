@@ -0,0 +1,79 @@
+package aoi
+
+/*
+修改对象类型
+
+例如玩家隐身(TriggerAndObserver -> Observer), 陷阱激活(0 -> Trigger)。
+SetObjType只通知类型变化前后可见关系有变化的对象, 不需要Leave再Enter:
+1. cb和EventHandler: 和Enter, Leave一样按行动人的视角, 之前不可见现在可见的通知EnterView, 反过来通知LeaveView,
+Trigger是行动人, TriggerType是修改后的类型
+2. Listener和可见集合: 和SetMask一样从观察者的视角通知EnterView和LeaveView
+*/
+
+// SetObjType 修改对象类型
+// 对象不存在时返回false
+func (m *AOIManager[ObjID, P]) SetObjType(id ObjID, ot ObjType, cb EventCallback[ObjID]) (ok bool) {
+	if m.recorder != nil {
+		var rec *LogRecord[ObjID, P]
+		rec, cb = m.record(OpObjType, id, 0, 0, 0, cb)
+		rec.Type = ot
+		defer func() { m.recordDone(rec, ok) }()
+	}
+	o, ok := m.objs[id]
+	if !ok {
+		return false
+	}
+	if o.ot == ot {
+		return true
+	}
+	var (
+		g       = m.grid(o.gridID)
+		related = map[ObjID]int{id: g.id}
+		before  map[seeEdge[ObjID]]struct{}
+		was     set[ObjID]
+	)
+	if m.directed() {
+		before = m.seeEdges(related)
+	}
+	if cb != nil || m.handler != nil {
+		was = m.visiblePairs(id, o, g)
+	}
+
+	g.del(id)
+	m.countType(o.ot, -1)
+	m.countType(ot, 1)
+	o.ot = ot
+	g.add(id, ot.IsObserver())
+
+	if m.directed() {
+		m.emitEdges(before, m.seeEdges(related), nil, nil, nil)
+	}
+	if was == nil {
+		return true
+	}
+	cb = m.eventCallback(id, o, g.id, g.id, false, cb)
+	now := m.visiblePairs(id, o, g)
+	for other := range was {
+		if !now.Contains(other) {
+			cb(LeaveView, other)
+		}
+	}
+	for other := range now {
+		if !was.Contains(other) {
+			cb(EnterView, other)
+		}
+	}
+	return true
+}
+
+// visiblePairs 相邻格子内和o可见的对象, 规则和Enter时的通知一致
+func (m *AOIManager[ObjID, P]) visiblePairs(id ObjID, o *obj[ObjID, P], g *Grid[ObjID, P]) set[ObjID] {
+	ret := make(set[ObjID])
+	filter := m.viewFilter(o, o.x, o.y, o.z)
+	for _, sg := range g.SurroundGrids() {
+		sg.invokeEvent(id, o.ot.IsObserver(), EnterView, func(_ EventType, other ObjID) {
+			ret[other] = struct{}{}
+		}, filter)
+	}
+	return ret
+}
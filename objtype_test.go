@@ -0,0 +1,182 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_SetObjType(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	var events []Event[int, int]
+	a.SetEventHandler(func(e *Event[int, int]) {
+		events = append(events, *e)
+	})
+	var cbEvents []LogEvent[int]
+	cb := func(event EventType, other int) {
+		cbEvents = append(cbEvents, LogEvent[int]{event, other})
+	}
+
+	l2 := &testListener{}
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 16, 16, Observer, nil, WithListener[int, int](l2))
+	a.Enter(3, 17, 17, Trigger, nil)
+	require.ElementsMatch(t, []int{1, 3}, l2.enter)
+	*l2 = testListener{}
+	events = nil
+	require.False(t, a.SetObjType(4, Trigger, cb))
+	require.True(t, a.SetObjType(1, TriggerAndObserver, cb))
+	require.Empty(t, cbEvents)
+
+	// 隐身, 观察者2看不到1了, 1还是观察者, 和其他人的可见关系不变
+	require.True(t, a.SetObjType(1, Observer, cb))
+	require.Equal(t, []int{1}, l2.leave)
+	require.Empty(t, cbEvents)
+	require.Empty(t, events)
+	require.ElementsMatch(t, []int{3}, a.VisibleTo(2))
+	require.ElementsMatch(t, []int{3}, a.VisibleTo(1))
+	require.False(t, a.ObjGrid(1).ObserverIDs().Contains(3))
+	require.True(t, a.ObjGrid(1).ObserverIDs().Contains(1))
+
+	// 1只是触发者, 和3互相不可见, 2又能看到1
+	require.True(t, a.SetObjType(1, Trigger, cb))
+	require.Equal(t, []LogEvent[int]{{LeaveView, 3}}, cbEvents)
+	require.Equal(t, []Event[int, int]{{
+		Type:    LeaveView,
+		Trigger: 1, TriggerType: Trigger, TriggerX: 15, TriggerY: 15,
+		Receiver: 3, ReceiverType: Trigger, ReceiverX: 17, ReceiverY: 17,
+		FromGrid: 11, ToGrid: 11,
+	}}, events)
+	require.Equal(t, []int{1}, l2.enter)
+	require.ElementsMatch(t, []int{1, 3}, a.VisibleTo(2))
+	require.ElementsMatch(t, []int{}, a.VisibleTo(1))
+	require.False(t, a.ObjGrid(1).ObserverIDs().Contains(1))
+
+	// 陷阱激活, 观察者2看到陷阱
+	a.Enter(4, 18, 18, 0, nil)
+	require.ElementsMatch(t, []int{1, 3}, a.VisibleTo(2))
+	require.True(t, a.SetObjType(4, Trigger, nil))
+	require.Equal(t, []int{1, 4}, l2.enter)
+	require.ElementsMatch(t, []int{1, 3, 4}, a.VisibleTo(2))
+
+	// 2不再是观察者, 和所有人都不可见
+	cbEvents, events = nil, nil
+	require.True(t, a.SetObjType(2, 0, cb))
+	require.ElementsMatch(t, []LogEvent[int]{{LeaveView, 1}, {LeaveView, 3}, {LeaveView, 4}}, cbEvents)
+	require.Len(t, events, 3)
+	for _, e := range events {
+		require.Equal(t, 2, e.Trigger)
+		require.False(t, e.ObserverView)
+	}
+	require.ElementsMatch(t, []int{1, 3, 4}, l2.leave[1:])
+	require.Empty(t, a.VisibleTo(2))
+}
+
+func TestAOI_SetObjTypeRandom(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10, WithVisibility())
+	require.Nil(t, err)
+
+	// 观察者视角的事件通过Listener收集
+	enter, leave := map[seeEdge[int]]struct{}{}, map[seeEdge[int]]struct{}{}
+	handler := WithHandler(func(e *Event[int, int]) {
+		edge := seeEdge[int]{e.Receiver, e.Trigger}
+		switch e.Type {
+		case EnterView:
+			enter[edge] = struct{}{}
+		case LeaveView:
+			leave[edge] = struct{}{}
+		}
+	})
+	ots := []ObjType{0, Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil, handler)
+	}
+	// 观察者视角的事件应该正好是修改前后可见集合的变化
+	visible := func() map[seeEdge[int]]struct{} {
+		edges := map[seeEdge[int]]struct{}{}
+		for id := range a.objs {
+			for _, other := range a.VisibleTo(id) {
+				edges[seeEdge[int]{id, other}] = struct{}{}
+			}
+		}
+		return edges
+	}
+	// cb按行动人的视角: 相邻格子内任意一方是观察者即可见
+	pairs := func(id int) map[int]bool {
+		ret := map[int]bool{}
+		a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+			if other != id && (a.objs[id].ot.IsObserver() || a.objs[other].ot.IsObserver()) {
+				ret[other] = true
+			}
+			return true
+		})
+		return ret
+	}
+	for i := 0; i < 1000; i++ {
+		id := rand.Intn(num)
+		if i%2 == 0 {
+			a.Move(id, rand.Intn(w), rand.Intn(h), nil)
+			continue
+		}
+		before, was := visible(), pairs(id)
+		enter, leave = map[seeEdge[int]]struct{}{}, map[seeEdge[int]]struct{}{}
+		cbEnter, cbLeave := map[int]bool{}, map[int]bool{}
+		a.SetObjType(id, ots[rand.Intn(len(ots))], func(event EventType, other int) {
+			switch event {
+			case EnterView:
+				cbEnter[other] = true
+			case LeaveView:
+				cbLeave[other] = true
+			default:
+				require.Fail(t, "unexpected event", "%v", event)
+			}
+		})
+		after := visible()
+		expectEnter, expectLeave := map[seeEdge[int]]struct{}{}, map[seeEdge[int]]struct{}{}
+		for edge := range after {
+			if _, ok := before[edge]; !ok {
+				expectEnter[edge] = struct{}{}
+			}
+		}
+		for edge := range before {
+			if _, ok := after[edge]; !ok {
+				expectLeave[edge] = struct{}{}
+			}
+		}
+		require.Equal(t, expectEnter, enter, fmt.Sprint(i))
+		require.Equal(t, expectLeave, leave, fmt.Sprint(i))
+
+		now := pairs(id)
+		expectCBEnter, expectCBLeave := map[int]bool{}, map[int]bool{}
+		for other := range now {
+			if !was[other] {
+				expectCBEnter[other] = true
+			}
+		}
+		for other := range was {
+			if !now[other] {
+				expectCBLeave[other] = true
+			}
+		}
+		require.Equal(t, expectCBEnter, cbEnter, fmt.Sprint(i))
+		require.Equal(t, expectCBLeave, cbLeave, fmt.Sprint(i))
+	}
+
+	for id, o := range a.objs {
+		var sees []int
+		a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+			if other != id && o.ot.IsObserver() && a.objs[other].ot.IsTrigger() {
+				sees = append(sees, other)
+			}
+			return true
+		})
+		require.ElementsMatch(t, sees, a.VisibleTo(id), fmt.Sprint(id))
+	}
+}
@@ -0,0 +1,206 @@
+package aoi
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+范围查询
+
+按对象的精确坐标查询矩形, 圆形范围内的对象和离某个点最近的k个对象,
+例如技能的范围伤害。只遍历和查询范围重叠的格子, 不会通知任何事件。
+
+ot不为0时只返回类型和ot有交集的对象, 例如ot为Trigger时不返回只是观察者的对象。
+环形地图按取模后的坐标计算, 查询范围不跨越地图边界。
+*/
+
+// QueryRect 坐标在矩形[minX, maxX]x[minY, maxY]内的对象
+func (m *AOIManager[ObjID, P]) QueryRect(minX, minY, maxX, maxY P, ot ObjType) []ObjID {
+	var ret []ObjID
+	m.foreachInRect(minX, minY, maxX, maxY, ot, func(id ObjID) bool {
+		ret = append(ret, id)
+		return true
+	})
+	return ret
+}
+
+// QueryCircle 坐标在以(x, y)为圆心, r为半径的圆内的对象
+func (m *AOIManager[ObjID, P]) QueryCircle(x, y P, r float64, ot ObjType) []ObjID {
+	var ret []ObjID
+	m.foreachInCircle(x, y, r, ot, func(id ObjID) bool {
+		ret = append(ret, id)
+		return true
+	})
+	return ret
+}
+
+// NearestK 离(x, y)最近的k个对象, 按距离从近到远排序
+// filter不为nil时只返回filter返回true的对象
+func (m *AOIManager[ObjID, P]) NearestK(x, y P, k int, filter func(id ObjID) bool) []ObjID {
+	if k <= 0 || len(m.objs) == 0 {
+		return nil
+	}
+	k = min(k, len(m.objs))
+	type candidate struct {
+		id ObjID
+		d  float64
+	}
+	var (
+		cands   []candidate
+		visited int // 已经遍历过的对象数, 包括被filter过滤的
+		row     = axisIndex(y, m.minY, m.maxY, m.gridH, m.row)
+		col     = axisIndex(x, m.minX, m.maxX, m.gridW, m.col)
+		maxRing = m.row
+		side    = float64(m.gridW)
+		slack   = 1 // 对象所在的格子和矩形格子下标的误差
+	)
+	if m.col > maxRing {
+		maxRing = m.col
+	}
+	if float64(m.gridH) < side {
+		side = float64(m.gridH)
+	}
+	if m.hex {
+		slack = 2
+	}
+	visit := func(_row, _col int) {
+		if _row < 0 || _row >= m.row || _col < 0 || _col >= m.col {
+			return
+		}
+		for layer := 0; layer < m.layer; layer++ {
+			g := m.grid(m.layerGridIndex(layer, _row, _col))
+			if g == nil {
+				continue
+			}
+			visited += len(g.objs)
+			for id := range g.objs {
+				if filter != nil && !filter(id) {
+					continue
+				}
+				ox, oy := m.queryPos(m.objs[id])
+				cands = append(cands, candidate{id: id, d: distanceSq(x, y, ox, oy)})
+			}
+		}
+	}
+	for ring := 0; ring <= maxRing; ring++ {
+		// 遍历和中心格子行列距离正好为ring的一圈格子
+		for _row := row - ring; _row <= row+ring; _row++ {
+			if _row == row-ring || _row == row+ring {
+				for _col := col - ring; _col <= col+ring; _col++ {
+					visit(_row, _col)
+				}
+				continue
+			}
+			visit(_row, col-ring)
+			if ring > 0 {
+				visit(_row, col+ring)
+			}
+		}
+		// 所有对象都遍历过了, 不需要再往外找
+		if visited == len(m.objs) {
+			break
+		}
+		if len(cands) < k {
+			continue
+		}
+		sort.Slice(cands, func(i, j int) bool { return cands[i].d < cands[j].d })
+		cands = cands[:k]
+		// 外面一圈的对象离(x, y)至少bound
+		bound := float64(ring+1-slack)*side - m.hysteresis
+		if bound > 0 && cands[k-1].d <= bound*bound {
+			break
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].d < cands[j].d })
+	if len(cands) > k {
+		cands = cands[:k]
+	}
+	ret := make([]ObjID, 0, len(cands))
+	for _, c := range cands {
+		ret = append(ret, c.id)
+	}
+	return ret
+}
+
+// foreachInRect 遍历矩形内类型和ot有交集的对象, f返回false时停止
+func (m *AOIManager[ObjID, P]) foreachInRect(minX, minY, maxX, maxY P, ot ObjType, f func(id ObjID) bool) {
+	m.foreachNearRect(minX, minY, maxX, maxY, func(id ObjID, o *obj[ObjID, P]) bool {
+		if !matchType(o.ot, ot) {
+			return true
+		}
+		x, y := m.queryPos(o)
+		if x < minX || x > maxX || y < minY || y > maxY {
+			return true
+		}
+		return f(id)
+	})
+}
+
+// foreachInCircle 遍历圆内类型和ot有交集的对象, f返回false时停止
+func (m *AOIManager[ObjID, P]) foreachInCircle(x, y P, r float64, ot ObjType, f func(id ObjID) bool) {
+	if r < 0 {
+		return
+	}
+	minX, minY := P(math.Floor(float64(x)-r)), P(math.Floor(float64(y)-r))
+	maxX, maxY := P(math.Ceil(float64(x)+r)), P(math.Ceil(float64(y)+r))
+	m.foreachNearRect(minX, minY, maxX, maxY, func(id ObjID, o *obj[ObjID, P]) bool {
+		if !matchType(o.ot, ot) {
+			return true
+		}
+		ox, oy := m.queryPos(o)
+		if distanceSq(x, y, ox, oy) > r*r {
+			return true
+		}
+		return f(id)
+	})
+}
+
+// foreachNearRect 遍历和矩形重叠的格子内的所有对象, f返回false时停止
+func (m *AOIManager[ObjID, P]) foreachNearRect(minX, minY, maxX, maxY P, f func(id ObjID, o *obj[ObjID, P]) bool) {
+	if minX > maxX || minY > maxY {
+		return
+	}
+	// 滞后距离内的对象可能还留在旁边的格子
+	if m.hysteresis > 0 {
+		minX, minY = P(math.Floor(float64(minX)-m.hysteresis)), P(math.Floor(float64(minY)-m.hysteresis))
+		maxX, maxY = P(math.Ceil(float64(maxX)+m.hysteresis)), P(math.Ceil(float64(maxY)+m.hysteresis))
+	}
+	var (
+		row0, row1 = axisIndex(minY, m.minY, m.maxY, m.gridH, m.row), axisIndex(maxY, m.minY, m.maxY, m.gridH, m.row)
+		col0, col1 = axisIndex(minX, m.minX, m.maxX, m.gridW, m.col), axisIndex(maxX, m.minX, m.maxX, m.gridW, m.col)
+	)
+	// 六边形格子错开半个格子, 外接矩形也比行高, 多看一圈
+	if m.hex {
+		row0, row1 = clampIndex(row0-1, m.row), clampIndex(row1+1, m.row)
+		col0, col1 = clampIndex(col0-1, m.col), clampIndex(col1+1, m.col)
+	}
+	for layer := 0; layer < m.layer; layer++ {
+		for row := row0; row <= row1; row++ {
+			for col := col0; col <= col1; col++ {
+				g := m.grid(m.layerGridIndex(layer, row, col))
+				if g == nil {
+					continue
+				}
+				for id := range g.objs {
+					if !f(id, m.objs[id]) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// queryPos 查询用的坐标, 环形地图取模到地图范围内
+func (m *AOIManager[ObjID, P]) queryPos(o *obj[ObjID, P]) (P, P) {
+	if m.wrap {
+		return wrapCoord(o.x, m.minX, m.maxX), wrapCoord(o.y, m.minY, m.maxY)
+	}
+	return o.x, o.y
+}
+
+// matchType ot为0时不过滤, 否则类型和ot有交集
+func matchType(t, ot ObjType) bool {
+	return ot == 0 || t&ot != 0
+}
@@ -0,0 +1,110 @@
+package aoi
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Query(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	a.Enter(1, 15, 15, Trigger, nil)
+	a.Enter(2, 20, 20, Observer, nil)
+	a.Enter(3, 25, 25, TriggerAndObserver, nil)
+	a.Enter(4, 80, 80, Trigger, nil)
+
+	require.ElementsMatch(t, []int{1, 2, 3}, a.QueryRect(15, 15, 25, 25, 0))
+	require.ElementsMatch(t, []int{1, 3}, a.QueryRect(15, 15, 25, 25, Trigger))
+	require.ElementsMatch(t, []int{2}, a.QueryRect(16, 16, 24, 24, 0))
+	require.Empty(t, a.QueryRect(30, 30, 20, 20, 0))
+
+	require.ElementsMatch(t, []int{2, 3}, a.QueryCircle(22, 22, 5, 0))
+	require.ElementsMatch(t, []int{2, 3}, a.QueryCircle(22, 22, 5, Observer))
+	require.ElementsMatch(t, []int{1, 2, 3, 4}, a.QueryCircle(50, 50, 100, 0))
+	require.Empty(t, a.QueryCircle(50, 50, -1, 0))
+
+	require.Equal(t, []int{3, 2}, a.NearestK(26, 26, 2, nil))
+	require.Equal(t, []int{4, 3, 1}, a.NearestK(100, 100, 3, func(id int) bool { return id != 2 }))
+	require.Equal(t, []int{1, 2, 3, 4}, a.NearestK(0, 0, 10, nil))
+	require.Empty(t, a.NearestK(0, 0, 0, nil))
+
+	// k比对象数大时遍历完所有对象就停止, 不会把整个地图的格子都走一遍
+	big, err := NewAOIManager[int](100000, 100000, 10, 10, WithSparse())
+	require.Nil(t, err)
+	big.Enter(1, 5, 5, Trigger, nil)
+	big.Enter(2, 15, 5, Trigger, nil)
+	require.Equal(t, []int{1, 2}, big.NearestK(0, 0, math.MaxInt, nil))
+	require.Equal(t, []int{2}, big.NearestK(0, 0, 2, func(id int) bool { return id == 2 }))
+}
+
+func TestAOI_QueryRandom(t *testing.T) {
+	const (
+		w, h = 200, 160
+		num  = 300
+	)
+	tests := map[string][]Option{
+		"square":     nil,
+		"hex":        {WithHex()},
+		"sparse":     {WithSparse()},
+		"hysteresis": {WithHysteresis(3)},
+		"wrap":       {WithWrap()},
+	}
+	for name, opts := range tests {
+		a, err := NewAOIManagerFrom[int, float64](-50, -30, w, h, 10, 8, opts...)
+		require.Nil(t, err)
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			x, y := rand.Float64()*w-50, rand.Float64()*h-30
+			a.Enter(id, x, y, ots[rand.Intn(len(ots))], nil)
+		}
+		// 移动一部分对象, 让滞后距离内的对象留在旁边的格子
+		for id := 0; id < num; id += 3 {
+			x, y := a.objs[id].x+rand.Float64()*4-2, a.objs[id].y+rand.Float64()*4-2
+			a.Move(id, x, y, nil)
+		}
+
+		for i := 0; i < 50; i++ {
+			x, y := rand.Float64()*w-50, rand.Float64()*h-30
+			r := rand.Float64() * 40
+			ot := ots[rand.Intn(len(ots))]
+
+			var rect, circle []int
+			for id, o := range a.objs {
+				if o.ot&ot == 0 {
+					continue
+				}
+				ox, oy := a.queryPos(o)
+				if ox >= x-r && ox <= x+r && oy >= y-r && oy <= y+r {
+					rect = append(rect, id)
+				}
+				if distanceSq(x, y, ox, oy) <= r*r {
+					circle = append(circle, id)
+				}
+			}
+			require.ElementsMatch(t, rect, a.QueryRect(x-r, y-r, x+r, y+r, ot), name)
+			require.ElementsMatch(t, circle, a.QueryCircle(x, y, r, ot), name)
+
+			k := rand.Intn(10) + 1
+			all := make([]int, 0, num)
+			for id := range a.objs {
+				if id%2 == 0 {
+					all = append(all, id)
+				}
+			}
+			dist := func(id int) float64 {
+				ox, oy := a.queryPos(a.objs[id])
+				return distanceSq(x, y, ox, oy)
+			}
+			sort.Slice(all, func(i, j int) bool { return dist(all[i]) < dist(all[j]) })
+			nearest := a.NearestK(x, y, k, func(id int) bool { return id%2 == 0 })
+			require.Len(t, nearest, k, name)
+			for i, id := range nearest {
+				require.Equal(t, dist(all[i]), dist(id), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package aoi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+/*
+操作日志和回放
+
+SetRecorder设置Recorder后, 每次Enter, Leave, Move, Teleport, SetObjType和Clear的参数, 返回值和产生的事件
+都会以一行json追加到io.Writer, 第一行是设置时的快照(State), Resize和Regrid之后也会记录一次快照。
+QA复现可见性问题时把日志交给Replayer, 从快照恢复一个新的管理器重新执行,
+检查每一步的返回值和事件是否一致, 也可以停在某一步输出格子的状态。
+
+同一次操作内事件的顺序不固定, 比较时不考虑顺序。
+MoveMany, SetMask, SetVisibleFunc等修改不会记录, 日志中间有这些操作时回放会不一致。
+记录时即使没有传回调也会计算事件, 会有额外的开销。
+*/
+
+// LogOp 操作类型
+type LogOp string
+
+const (
+	OpState    LogOp = "state"    // 快照, 日志的第一行
+	OpEnter    LogOp = "enter"    // Enter
+	OpLeave    LogOp = "leave"    // Leave
+	OpMove     LogOp = "move"     // Move
+	OpTeleport LogOp = "teleport" // Teleport
+	OpObjType  LogOp = "objtype"  // SetObjType
+	OpClear    LogOp = "clear"    // Clear
+)
+
+// LogEvent 操作产生的事件, 同EventCallback的参数
+type LogEvent[T ObjID] struct {
+	Type  EventType `json:"type"`
+	Other T         `json:"other"`
+}
+
+// LogRecord 一条操作日志
+type LogRecord[T ObjID, P Coord] struct {
+	Op         LogOp         `json:"op"`
+	ID         T             `json:"id"`
+	X          P             `json:"x,omitempty"`
+	Y          P             `json:"y,omitempty"`
+	Z          P             `json:"z,omitempty"`
+	Type       ObjType       `json:"type,omitempty"`        // Enter和SetObjType的类型
+	ViewRadius float64       `json:"view_radius,omitempty"` // Enter的可见半径
+	Layer      uint64        `json:"layer,omitempty"`       // Enter的层
+	Sight      uint64        `json:"sight,omitempty"`       // Enter能看到的层
+	OK         bool          `json:"ok"`                    // 返回值
+	Events     []LogEvent[T] `json:"events,omitempty"`
+	State      *State[T, P]  `json:"state,omitempty"` // OpState的快照
+}
+
+// Recorder 操作日志, 每行一条json
+type Recorder struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder 构造, 日志写到w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err 第一次写日志失败的错误, 失败后不再记录
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) write(v any) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(v)
+}
+
+// SetRecorder 设置操作日志, 先写入当前状态的快照, nil取消
+func (m *AOIManager[ObjID, P]) SetRecorder(r *Recorder) {
+	m.recorder = r
+	if r != nil {
+		m.recordState()
+	}
+}
+
+// recordState 记录当前状态的快照
+func (m *AOIManager[ObjID, P]) recordState() {
+	m.recorder.write(&LogRecord[ObjID, P]{Op: OpState, OK: true, State: m.State()})
+}
+
+// record 开始记录一次操作, 返回的cb同时记录事件
+func (m *AOIManager[ObjID, P]) record(op LogOp, id ObjID, x, y, z P, cb EventCallback[ObjID]) (*LogRecord[ObjID, P], EventCallback[ObjID]) {
+	rec := &LogRecord[ObjID, P]{Op: op, ID: id, X: x, Y: y, Z: z}
+	return rec, func(event EventType, other ObjID) {
+		rec.Events = append(rec.Events, LogEvent[ObjID]{Type: event, Other: other})
+		if cb != nil {
+			cb(event, other)
+		}
+	}
+}
+
+// recordDone 操作结束, 写入日志
+func (m *AOIManager[ObjID, P]) recordDone(rec *LogRecord[ObjID, P], ok bool) {
+	rec.OK = ok
+	if ok && rec.Op == OpEnter {
+		o := m.objs[rec.ID]
+		rec.ViewRadius, rec.Layer, rec.Sight = o.viewRadius, o.layer, o.sight
+	}
+	m.recorder.write(rec)
+}
+
+// Replayer 回放操作日志
+type Replayer[T ObjID, P Coord] struct {
+	records []LogRecord[T, P]
+	m       *AOIManager[T, P]
+	pos     int
+}
+
+// NewReplayer 读取全部日志, 并执行第一条快照
+func NewReplayer[T ObjID, P Coord](r io.Reader) (*Replayer[T, P], error) {
+	p := &Replayer[T, P]{}
+	dec := json.NewDecoder(r)
+	for {
+		var rec LogRecord[T, P]
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(p.records), err)
+		}
+		p.records = append(p.records, rec)
+	}
+	if len(p.records) == 0 || p.records[0].Op != OpState {
+		return nil, fmt.Errorf("log should start with state")
+	}
+	if err := p.Step(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Len 日志的条数, 包括快照
+func (p *Replayer[T, P]) Len() int {
+	return len(p.records)
+}
+
+// Pos 已经执行的条数, 下一条要执行的日志下标
+func (p *Replayer[T, P]) Pos() int {
+	return p.pos
+}
+
+// Record 第i条日志
+func (p *Replayer[T, P]) Record(i int) LogRecord[T, P] {
+	return p.records[i]
+}
+
+// Manager 回放用的管理器, 可以用来查看当前的状态
+// 修改它会导致后面的回放不一致
+func (p *Replayer[T, P]) Manager() *AOIManager[T, P] {
+	return p.m
+}
+
+// Step 执行下一条日志, 返回值或者事件和日志不一致时返回错误
+func (p *Replayer[T, P]) Step() error {
+	if p.pos >= len(p.records) {
+		return io.EOF
+	}
+	rec := &p.records[p.pos]
+	var (
+		ok     = true
+		events []LogEvent[T]
+		cb     = func(event EventType, other T) {
+			events = append(events, LogEvent[T]{Type: event, Other: other})
+		}
+	)
+	switch rec.Op {
+	case OpState:
+		m, err := Restore(rec.State)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", p.pos, err)
+		}
+		p.m = m
+	case OpEnter:
+		ok = p.m.enter(rec.ID, rec.X, rec.Y, rec.Z, rec.Type, cb, WithViewRadius(rec.ViewRadius), WithMask(rec.Layer, rec.Sight))
+	case OpLeave:
+		ok = p.m.Leave(rec.ID, cb)
+	case OpMove:
+		ok = p.m.move(rec.ID, rec.X, rec.Y, rec.Z, false, cb)
+	case OpTeleport:
+		ok = p.m.move(rec.ID, rec.X, rec.Y, rec.Z, true, cb)
+	case OpObjType:
+		ok = p.m.SetObjType(rec.ID, rec.Type, cb)
+	case OpClear:
+		p.m.Clear()
+	default:
+		return fmt.Errorf("record %d: unknown op %q", p.pos, rec.Op)
+	}
+	p.pos++
+	if ok != rec.OK {
+		return fmt.Errorf("record %d %s %v: ok %v, want %v", p.pos-1, rec.Op, rec.ID, ok, rec.OK)
+	}
+	if diff := diffEvents(rec.Events, events); diff != "" {
+		return fmt.Errorf("record %d %s %v: events differ: %s", p.pos-1, rec.Op, rec.ID, diff)
+	}
+	return nil
+}
+
+// StepTo 执行到第i条日志之前(Pos() == i), i比当前位置小时从快照重新执行
+func (p *Replayer[T, P]) StepTo(i int) error {
+	if i < 1 || i > len(p.records) {
+		return fmt.Errorf("step %d out of range [1, %d]", i, len(p.records))
+	}
+	if i < p.pos {
+		p.pos = 0
+	}
+	for p.pos < i {
+		if err := p.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 执行剩下的所有日志
+func (p *Replayer[T, P]) Run() error {
+	return p.StepTo(len(p.records))
+}
+
+// Dump 输出当前每个格子内的对象, 对象按id排序
+func (p *Replayer[T, P]) Dump() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "record %d/%d, %d objs\n", p.pos, len(p.records), p.m.Len())
+	for _, g := range p.m.AllGrids() {
+		if len(g.objs) == 0 {
+			continue
+		}
+		objs := make([]string, 0, len(g.objs))
+		for id := range g.objs {
+			o := p.m.objs[id]
+			objs = append(objs, fmt.Sprintf("%v(%v,%v,%v):%d", id, o.x, o.y, o.z, o.ot))
+		}
+		sort.Strings(objs)
+		fmt.Fprintf(&sb, "%s %s\n", g, strings.Join(objs, " "))
+	}
+	return sb.String()
+}
+
+// diffEvents 不考虑顺序比较两组事件, 一致时返回空字符串
+func diffEvents[T ObjID](want, got []LogEvent[T]) string {
+	count := make(map[LogEvent[T]]int, len(want))
+	for _, e := range want {
+		count[e]++
+	}
+	for _, e := range got {
+		count[e]--
+	}
+	var missing, extra []string
+	for e, n := range count {
+		for ; n > 0; n-- {
+			missing = append(missing, fmt.Sprint(e))
+		}
+		for ; n < 0; n++ {
+			extra = append(extra, fmt.Sprint(e))
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return ""
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return fmt.Sprintf("missing %v, extra %v", missing, extra)
+}
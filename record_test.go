@@ -0,0 +1,120 @@
+package aoi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Recorder(t *testing.T) {
+	const num = 100
+	a, err := NewAOIManager[int, float64](200, 200, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	// 设置日志之前的对象在快照里
+	for id := 0; id < num/2; id++ {
+		a.Enter(id, rand.Float64()*200, rand.Float64()*200, ots[rand.Intn(len(ots))], nil)
+	}
+
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	a.SetRecorder(r)
+	for id := num / 2; id < num; id++ {
+		var opts []EnterOption
+		if id%3 == 0 {
+			opts = append(opts, WithViewRadius(20), WithMask(1, 3))
+		}
+		a.Enter(id, rand.Float64()*200, rand.Float64()*200, ots[rand.Intn(len(ots))], nil, opts...)
+	}
+	a.Enter(0, 0, 0, Trigger, nil)
+	for i := 0; i < 300; i++ {
+		id := rand.Intn(num + 10)
+		x, y := rand.Float64()*220-10, rand.Float64()*220-10
+		switch rand.Intn(10) {
+		case 0:
+			a.Leave(id, nil)
+		case 1:
+			a.Teleport(id, x, y, nil)
+		case 2:
+			a.SetObjType(id, ots[rand.Intn(len(ots))], nil)
+		default:
+			a.Move(id, x, y, nil)
+		}
+	}
+	a.Clear()
+	a.Enter(1, 10, 10, TriggerAndObserver, nil)
+	a.SetRecorder(nil)
+	a.Enter(2, 10, 10, TriggerAndObserver, nil)
+	require.Nil(t, r.Err())
+
+	log := buf.String()
+	p, err := NewReplayer[int, float64](strings.NewReader(log))
+	require.Nil(t, err)
+	require.Equal(t, 1, p.Pos())
+	require.Equal(t, num/2+1+300+2+1, p.Len())
+	require.Equal(t, num/2, p.Manager().Len())
+	require.Equal(t, OpEnter, p.Record(1).Op)
+	var objTypes int
+	for i := 0; i < p.Len(); i++ {
+		if p.Record(i).Op == OpObjType {
+			objTypes++
+		}
+	}
+	require.NotZero(t, objTypes)
+
+	// 停在某一步
+	require.Nil(t, p.StepTo(num/2+2))
+	require.Equal(t, num, p.Manager().Len())
+	dump := p.Dump()
+	require.True(t, strings.HasPrefix(dump, "record 52/"), dump)
+
+	require.Nil(t, p.Run())
+	require.Equal(t, io.EOF, p.Step())
+	require.Equal(t, []int{1}, keys(p.Manager().ObjGrid(1).ObjIDs()))
+
+	// 回到前面重新执行
+	require.Nil(t, p.StepTo(num/2+2))
+	require.Equal(t, dump, p.Dump())
+	require.NotNil(t, p.StepTo(0))
+
+	// 事件不一致
+	lines := strings.Split(log, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, `"op":"move"`) && strings.Contains(line, `"events":[{"type":`) {
+			lines[i] = strings.Replace(line, `"events":[{"type":`, `"events":[{"type":9,"other":1},{"type":`, 1)
+			break
+		}
+	}
+	p, err = NewReplayer[int, float64](strings.NewReader(strings.Join(lines, "\n")))
+	require.Nil(t, err)
+	err = p.Run()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "events differ")
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestAOI_RecorderError(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	r := NewRecorder(errWriter{})
+	a.SetRecorder(r)
+	require.True(t, a.Enter(1, 10, 10, TriggerAndObserver, nil))
+	require.NotNil(t, r.Err())
+
+	_, err = NewReplayer[int, int](strings.NewReader(""))
+	require.NotNil(t, err)
+	_, err = NewReplayer[int, int](strings.NewReader(`{"op":"move","id":1}`))
+	require.NotNil(t, err)
+	_, err = NewReplayer[int, int](strings.NewReader(`{"op":`))
+	require.NotNil(t, err)
+}
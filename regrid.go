@@ -0,0 +1,63 @@
+package aoi
+
+/*
+调整地图范围和格子大小
+
+地图扩大(例如攻城战开放新的区域)或者调整格子大小时, 不需要重新创建管理器再让所有对象重新进入。
+Resize修改地图范围, Regrid修改格子宽高, 都在原地重建格子和相邻关系,
+对象保留原来的坐标, 超出新地图范围的对象放到边界的格子。
+
+重建前后可见关系的净变化和MoveMany一样从观察者的视角通知EventHandler, h和Listener, 可见关系没有变化时不通知。
+FromGrid和ToGrid都是Trigger在新地图上所在的格子。
+开启滞后时所有对象都重新放到坐标所在的格子。
+*/
+
+// Resize 修改地图范围, 格子大小不变
+// 参数不合法时返回错误, 不做任何修改
+func (m *AOIManager[ObjID, P]) Resize(x, y, width, height P, h EventHandler[ObjID, P]) error {
+	c := m.config()
+	c.MinX, c.MinY, c.Width, c.Height = x, y, width, height
+	return m.rebuild(&c, h)
+}
+
+// Regrid 修改格子宽高, 地图范围不变
+// 参数不合法时返回错误, 不做任何修改
+func (m *AOIManager[ObjID, P]) Regrid(gridW, gridH P, h EventHandler[ObjID, P]) error {
+	c := m.config()
+	c.GridW, c.GridH = gridW, gridH
+	return m.rebuild(&c, h)
+}
+
+// rebuild 按新的配置重建格子, 重新放置所有对象并通知可见关系的变化
+func (m *AOIManager[ObjID, P]) rebuild(c *StateConfig[P], h EventHandler[ObjID, P]) error {
+	n, err := newAOIManager[ObjID, P](c.MinX, c.MinY, c.MinZ, c.Width, c.Height, c.Depth, c.GridW, c.GridH, c.GridD, c.options()...)
+	if err != nil {
+		return err
+	}
+	regrid := func() {
+		m.minX, m.minY, m.maxX, m.maxY = n.minX, n.minY, n.maxX, n.maxY
+		m.minZ, m.maxZ = n.minZ, n.maxZ
+		m.gridW, m.gridH, m.gridD = n.gridW, n.gridH, n.gridD
+		m.row, m.col, m.layer = n.row, n.col, n.layer
+		m.grids, m.sparse = n.grids, n.sparse
+		for id, o := range m.objs {
+			g := m.acquireGrid(o.x, o.y, o.z)
+			o.gridID = g.id
+			g.add(id, o.ot.IsObserver())
+		}
+	}
+	if h == nil && m.handler == nil && m.listened == 0 && !m.visibility {
+		regrid()
+	} else {
+		ids := make([]ObjID, 0, len(m.objs))
+		for id := range m.objs {
+			ids = append(ids, id)
+		}
+		m.UpdateVisible(ids, regrid, h)
+	}
+	// 操作日志不记录重建的过程, 直接记录重建后的快照
+	if m.recorder != nil {
+		m.recordState()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package aoi
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Resize(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	a.Enter(1, 95, 55, TriggerAndObserver, nil)
+	a.Enter(2, 85, 55, TriggerAndObserver, nil)
+	// 在地图外面, 放在边界的格子
+	a.Enter(3, 130, 55, Trigger, nil)
+	require.ElementsMatch(t, []int{2, 3}, a.VisibleTo(1))
+
+	var events []Event[int, int]
+	h := func(e *Event[int, int]) {
+		events = append(events, *e)
+	}
+	require.NotNil(t, a.Resize(0, 0, 0, 100, h))
+	require.Equal(t, 100, a.maxX)
+
+	// 地图扩大, 3回到自己的位置, 离开1和2的视野
+	require.Nil(t, a.Resize(0, 0, 200, 100, h))
+	require.Equal(t, 20, a.col)
+	require.Len(t, a.AllGrids(), 200)
+	require.ElementsMatch(t, []Event[int, int]{{
+		Type:    LeaveView,
+		Trigger: 3, TriggerType: Trigger, TriggerX: 130, TriggerY: 55,
+		Receiver: 1, ReceiverType: TriggerAndObserver, ReceiverX: 95, ReceiverY: 55,
+		FromGrid: 113, ToGrid: 113,
+		ObserverView: true,
+	}, {
+		Type:    LeaveView,
+		Trigger: 3, TriggerType: Trigger, TriggerX: 130, TriggerY: 55,
+		Receiver: 2, ReceiverType: TriggerAndObserver, ReceiverX: 85, ReceiverY: 55,
+		FromGrid: 113, ToGrid: 113,
+		ObserverView: true,
+	}}, events)
+	require.ElementsMatch(t, []int{2}, a.VisibleTo(1))
+	require.Equal(t, 113, a.ObjGrid(3).ID())
+	require.Empty(t, a.VisibleBy(3))
+
+	// 可见关系没有变化时不通知
+	events = nil
+	require.Nil(t, a.Resize(-100, -100, 300, 200, h))
+	require.Empty(t, events)
+	require.Equal(t, 3, a.Len())
+}
+
+func TestAOI_Regrid(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	for _, opts := range [][]Option{nil, {WithSparse()}, {WithHex()}, {WithHysteresis(2)}} {
+		opts = append(opts, WithVisibility())
+		a, err := NewAOIManager[int](w, h, 10, 10, opts...)
+		require.Nil(t, err)
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		listeners := map[int]*testListener{}
+		for id := 0; id < num; id++ {
+			l := &testListener{}
+			listeners[id] = l
+			a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil, WithListener[int, int](l))
+		}
+		before := map[int][]int{}
+		for id := range a.objs {
+			before[id] = a.VisibleTo(id)
+			*listeners[id] = testListener{}
+		}
+
+		require.NotNil(t, a.Regrid(-1, 10, nil))
+		require.Nil(t, a.Regrid(25, 15, nil))
+
+		// 和用新格子重新进入的结果一致
+		b, err := NewAOIManager[int](w, h, 25, 15, opts...)
+		require.Nil(t, err)
+		for id, o := range a.objs {
+			b.Enter(id, o.x, o.y, o.ot, nil)
+		}
+		require.Equal(t, b.String(), a.String())
+		for id := range a.objs {
+			require.Equal(t, b.ObjGrid(id).ID(), a.ObjGrid(id).ID())
+			now := a.VisibleTo(id)
+			require.ElementsMatch(t, b.VisibleTo(id), now)
+
+			// 只通知净变化
+			l := listeners[id]
+			require.Empty(t, l.update)
+			for _, other := range l.enter {
+				require.False(t, slices.Contains(before[id], other))
+				require.True(t, slices.Contains(now, other))
+			}
+			for _, other := range l.leave {
+				require.True(t, slices.Contains(before[id], other))
+				require.False(t, slices.Contains(now, other))
+			}
+			require.Equal(t, len(now), len(before[id])+len(l.enter)-len(l.leave))
+		}
+	}
+}
+
+func TestAOI_RegridRecorder(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	var buf bytes.Buffer
+	a.SetRecorder(NewRecorder(&buf))
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	require.Nil(t, a.Regrid(20, 20, nil))
+	a.Enter(2, 35, 15, TriggerAndObserver, nil)
+
+	p, err := NewReplayer[int, int](&buf)
+	require.Nil(t, err)
+	require.Equal(t, 4, p.Len())
+	require.Nil(t, p.Run())
+	require.Equal(t, a.String(), p.Manager().String())
+}
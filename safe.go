@@ -0,0 +1,343 @@
+package aoi
+
+import "sync"
+
+/*
+并发安全的aoi
+
+SafeAOIManager用读写锁包装AOIManager, 写操作(Enter, Leave, Move等)加写锁, 查询加读锁。
+所有回调(EventCallback, EventHandler, Listener)都先在锁内记录下来, 解锁后在调用方的goroutine里按顺序回调,
+操作返回时这次操作产生的回调都已经执行完, 回调里可以再调用SafeAOIManager, 不会死锁。
+不同goroutine的操作产生的回调可能同时执行, 顺序也可能和操作的顺序不一致,
+同一个Listener或者EventHandler会被多个goroutine调用时需要自己加锁。
+
+***注意***
+包装之后不要再直接使用原来的AOIManager, 也不要在原来的AOIManager上设置回调。
+*/
+
+var _ Manager[int, int] = (*SafeAOIManager[int, int])(nil)
+
+// pendingEvent 锁内记录下来的回调
+type pendingEvent[T ObjID, P Coord] struct {
+	cb    EventCallback[T]
+	event EventType
+	other T
+
+	handler  EventHandler[T, P]
+	listener Listener[T, P]
+	e        Event[T, P]
+}
+
+func (p *pendingEvent[T, P]) invoke() {
+	switch {
+	case p.cb != nil:
+		p.cb(p.event, p.other)
+	case p.handler != nil:
+		p.handler(&p.e)
+	case p.listener != nil:
+		notify(p.listener, &p.e)
+	}
+}
+
+// SafeAOIManager 并发安全的aoi管理器
+type SafeAOIManager[T ObjID, P Coord] struct {
+	mu      sync.RWMutex
+	m       *AOIManager[T, P]
+	handler EventHandler[T, P]   // 结构化事件回调
+	pending []pendingEvent[T, P] // 锁内记录的回调, 解锁后执行
+}
+
+// NewSafeAOIManager 包装m
+func NewSafeAOIManager[T ObjID, P Coord](m *AOIManager[T, P]) *SafeAOIManager[T, P] {
+	return &SafeAOIManager[T, P]{m: m}
+}
+
+// Enter 进入
+// eventType 只会是EnterView
+func (s *SafeAOIManager[T, P]) Enter(id T, posX, posY P, ot ObjType, cb EventCallback[T], opts ...EnterOption) bool {
+	var eo enterOptions
+	for _, opt := range opts {
+		opt(&eo)
+	}
+	l, ok := listenerOf[T, P](&eo)
+	if !ok {
+		return false
+	}
+	if l != nil {
+		opts = append(opts, WithListener[T, P](s.safeListener(l)))
+	}
+	s.mu.Lock()
+	ok = s.m.Enter(id, posX, posY, ot, s.record(cb), opts...)
+	return s.unlock(ok)
+}
+
+// Leave 离开
+// event 只会是LeaveView
+func (s *SafeAOIManager[T, P]) Leave(id T, cb EventCallback[T]) bool {
+	s.mu.Lock()
+	ok := s.m.Leave(id, s.record(cb))
+	return s.unlock(ok)
+}
+
+// Move 移动
+func (s *SafeAOIManager[T, P]) Move(id T, toPosX, toPosY P, cb EventCallback[T]) bool {
+	s.mu.Lock()
+	ok := s.m.Move(id, toPosX, toPosY, s.record(cb))
+	return s.unlock(ok)
+}
+
+// Teleport 瞬移
+func (s *SafeAOIManager[T, P]) Teleport(id T, toPosX, toPosY P, cb EventCallback[T]) bool {
+	s.mu.Lock()
+	ok := s.m.Teleport(id, toPosX, toPosY, s.record(cb))
+	return s.unlock(ok)
+}
+
+// MoveMany 批量移动, 返回移动的对象数
+func (s *SafeAOIManager[T, P]) MoveMany(ops []MoveOp[T, P], h EventHandler[T, P]) int {
+	s.mu.Lock()
+	n := s.m.MoveMany(ops, s.recordHandler(h))
+	s.unlock(true)
+	return n
+}
+
+// SetObjType 修改对象类型
+func (s *SafeAOIManager[T, P]) SetObjType(id T, ot ObjType, cb EventCallback[T]) bool {
+	s.mu.Lock()
+	ok := s.m.SetObjType(id, ot, s.record(cb))
+	return s.unlock(ok)
+}
+
+// SetMask 修改对象所在的层和能看到的层
+func (s *SafeAOIManager[T, P]) SetMask(id T, layer, sight uint64, h EventHandler[T, P]) bool {
+	s.mu.Lock()
+	ok := s.m.SetMask(id, layer, sight, s.recordHandler(h))
+	return s.unlock(ok)
+}
+
+// SetVisibleFunc 设置自定义可见规则, nil取消
+// f在写锁内调用, 不能再调用s的方法
+func (s *SafeAOIManager[T, P]) SetVisibleFunc(f VisibleFunc[T], h EventHandler[T, P]) {
+	s.mu.Lock()
+	s.m.SetVisibleFunc(f, s.recordHandler(h))
+	s.unlock(true)
+}
+
+// UpdateVisible 执行update, 重新计算和ids相关的可见关系并通知变化
+// update在写锁内调用, 不能再调用s的方法
+func (s *SafeAOIManager[T, P]) UpdateVisible(ids []T, update func(), h EventHandler[T, P]) {
+	s.mu.Lock()
+	s.m.UpdateVisible(ids, update, s.recordHandler(h))
+	s.unlock(true)
+}
+
+// Resize 修改地图范围
+func (s *SafeAOIManager[T, P]) Resize(x, y, width, height P, h EventHandler[T, P]) error {
+	s.mu.Lock()
+	err := s.m.Resize(x, y, width, height, s.recordHandler(h))
+	s.unlock(true)
+	return err
+}
+
+// Regrid 修改格子宽高
+func (s *SafeAOIManager[T, P]) Regrid(gridW, gridH P, h EventHandler[T, P]) error {
+	s.mu.Lock()
+	err := s.m.Regrid(gridW, gridH, s.recordHandler(h))
+	s.unlock(true)
+	return err
+}
+
+// State 当前状态的快照
+func (s *SafeAOIManager[T, P]) State() *State[T, P] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.State()
+}
+
+// Clear 清空
+func (s *SafeAOIManager[T, P]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m.Clear()
+}
+
+// SetEventHandler 设置结构化事件回调, nil取消
+func (s *SafeAOIManager[T, P]) SetEventHandler(h EventHandler[T, P]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = h
+	if h == nil {
+		s.m.SetEventHandler(nil)
+		return
+	}
+	s.m.SetEventHandler(func(e *Event[T, P]) {
+		s.pending = append(s.pending, pendingEvent[T, P]{handler: s.handler, e: *e})
+	})
+}
+
+// SetListener 设置对象的事件监听, nil取消
+func (s *SafeAOIManager[T, P]) SetListener(id T, l Listener[T, P]) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l == nil {
+		return s.m.SetListener(id, nil)
+	}
+	return s.m.SetListener(id, s.safeListener(l))
+}
+
+// SetHandler 设置对象的事件回调, nil取消
+func (s *SafeAOIManager[T, P]) SetHandler(id T, h EventHandler[T, P]) bool {
+	if h == nil {
+		return s.SetListener(id, nil)
+	}
+	return s.SetListener(id, h)
+}
+
+// Around 相邻格子内的其他对象, 对象不存在时返回nil
+func (s *SafeAOIManager[T, P]) Around(id T) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g := s.m.ObjGrid(id)
+	if g == nil {
+		return nil
+	}
+	ret := make([]T, 0)
+	g.ForeachInSurroundGrids(func(other T) bool {
+		if other != id {
+			ret = append(ret, other)
+		}
+		return true
+	})
+	return ret
+}
+
+// SetRecorder 设置操作日志, nil取消
+func (s *SafeAOIManager[T, P]) SetRecorder(r *Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m.SetRecorder(r)
+}
+
+// Has 对象是否存在
+func (s *SafeAOIManager[T, P]) Has(id T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Has(id)
+}
+
+// Pos 对象的坐标, 对象不存在时ok为false
+func (s *SafeAOIManager[T, P]) Pos(id T) (x, y P, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Pos(id)
+}
+
+// Type 对象的类型, 对象不存在时ok为false
+func (s *SafeAOIManager[T, P]) Type(id T) (ObjType, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Type(id)
+}
+
+// Len 对象总数
+func (s *SafeAOIManager[T, P]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Len()
+}
+
+// Count 类型和ot有交集的对象数, ot为0时是对象总数
+func (s *SafeAOIManager[T, P]) Count(ot ObjType) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Count(ot)
+}
+
+// ForeachObj 遍历所有对象, f返回false时停止
+// f在读锁内调用, 不能再调用s的方法
+func (s *SafeAOIManager[T, P]) ForeachObj(f func(id T, x, y P, ot ObjType) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.m.ForeachObj(f)
+}
+
+// QueryRect 坐标在矩形范围内的对象
+func (s *SafeAOIManager[T, P]) QueryRect(minX, minY, maxX, maxY P, ot ObjType) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.QueryRect(minX, minY, maxX, maxY, ot)
+}
+
+// QueryCircle 坐标在圆内的对象
+func (s *SafeAOIManager[T, P]) QueryCircle(x, y P, r float64, ot ObjType) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.QueryCircle(x, y, r, ot)
+}
+
+// NearestK 离(x, y)最近的k个对象
+// filter在读锁内调用, 不能再调用s的方法
+func (s *SafeAOIManager[T, P]) NearestK(x, y P, k int, filter func(id T) bool) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.NearestK(x, y, k, filter)
+}
+
+// VisibleTo id能看到的对象, 需要开启WithVisibility
+func (s *SafeAOIManager[T, P]) VisibleTo(id T) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.VisibleTo(id)
+}
+
+// VisibleBy 能看到id的观察者, 需要开启WithVisibility
+func (s *SafeAOIManager[T, P]) VisibleBy(id T) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.VisibleBy(id)
+}
+
+// String 格式化输出
+func (s *SafeAOIManager[T, P]) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.String()
+}
+
+// record 把cb包装成锁内只记录不执行的回调
+func (s *SafeAOIManager[T, P]) record(cb EventCallback[T]) EventCallback[T] {
+	if cb == nil {
+		return nil
+	}
+	return func(event EventType, other T) {
+		s.pending = append(s.pending, pendingEvent[T, P]{cb: cb, event: event, other: other})
+	}
+}
+
+// recordHandler 把h包装成锁内只记录不执行的EventHandler
+func (s *SafeAOIManager[T, P]) recordHandler(h EventHandler[T, P]) EventHandler[T, P] {
+	if h == nil {
+		return nil
+	}
+	return func(e *Event[T, P]) {
+		s.pending = append(s.pending, pendingEvent[T, P]{handler: h, e: *e})
+	}
+}
+
+// unlock 取出这次操作记录的回调, 解锁后在调用方的goroutine里执行
+func (s *SafeAOIManager[T, P]) unlock(ok bool) bool {
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	for i := range pending {
+		pending[i].invoke()
+	}
+	return ok
+}
+
+// safeListener 锁内只记录不执行的Listener
+func (s *SafeAOIManager[T, P]) safeListener(l Listener[T, P]) Listener[T, P] {
+	return EventHandler[T, P](func(e *Event[T, P]) {
+		s.pending = append(s.pending, pendingEvent[T, P]{listener: l, e: *e})
+	})
+}
@@ -0,0 +1,195 @@
+package aoi
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeAOI_Reentrant(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewSafeAOIManager(a)
+
+	s.Enter(1, 15, 15, TriggerAndObserver, nil)
+	// 回调里再调用管理器不会死锁
+	var around []int
+	require.True(t, s.Enter(2, 25, 15, TriggerAndObserver, func(event EventType, other int) {
+		around = s.Around(other)
+		s.Move(other, 16, 16, nil)
+	}))
+	require.Equal(t, []int{2}, around)
+
+	var got []int
+	s.SetEventHandler(func(e *Event[int, int]) {
+		got = append(got, e.Receiver)
+		s.Around(e.Trigger)
+	})
+	l := &testListener{}
+	require.True(t, s.SetListener(1, l))
+	s.Move(2, 26, 16, nil)
+	require.Equal(t, []int{1}, got)
+	require.Equal(t, []int{2}, l.update)
+
+	l3 := &testListener{}
+	s.Enter(3, 15, 25, Observer, nil, WithListener[int, int](l3))
+	require.ElementsMatch(t, []int{1, 2}, l3.enter)
+
+	require.Nil(t, s.Around(4))
+	s.Clear()
+	require.Nil(t, s.Around(1))
+}
+
+func TestSafeAOI_Visible(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	s := NewSafeAOIManager(a)
+	s.Enter(1, 15, 15, TriggerAndObserver, nil)
+	s.Enter(2, 25, 15, TriggerAndObserver, nil)
+
+	// 回调在锁外执行, 可以再调用管理器
+	var events []Event[int, int]
+	h := func(e *Event[int, int]) {
+		require.True(t, s.Has(e.Trigger))
+		events = append(events, *e)
+	}
+	require.Equal(t, 1, s.MoveMany([]MoveOp[int, int]{{2, 85, 85}}, h))
+	require.Len(t, events, 2)
+	require.Equal(t, LeaveView, events[0].Type)
+
+	events = nil
+	require.Equal(t, 1, s.MoveMany([]MoveOp[int, int]{{2, 25, 15}}, h))
+	require.Len(t, events, 2)
+	require.Equal(t, EnterView, events[0].Type)
+
+	// 双方都不是观察者时互相不可见, 最后1隐身
+	var cbEvents []EventType
+	cb := func(event EventType, other int) {
+		require.True(t, s.Has(other))
+		cbEvents = append(cbEvents, event)
+	}
+	require.True(t, s.SetObjType(2, 0, cb))
+	require.Empty(t, cbEvents)
+	require.True(t, s.SetObjType(1, 0, cb))
+	require.Equal(t, []EventType{LeaveView}, cbEvents)
+	require.True(t, s.SetObjType(1, Observer, cb))
+	require.Equal(t, []EventType{LeaveView, EnterView}, cbEvents)
+	require.True(t, s.SetObjType(2, TriggerAndObserver, nil))
+	require.False(t, s.SetObjType(3, Observer, cb))
+
+	// 2对所有人隐身, 1看不到2
+	events = nil
+	require.True(t, s.SetMask(2, 0, AllLayers, h))
+	require.Len(t, events, 1)
+	require.Equal(t, 1, events[0].Receiver)
+	require.Empty(t, s.VisibleTo(1))
+
+	events = nil
+	s.SetVisibleFunc(func(observer, target int) bool { return false }, h)
+	require.Len(t, events, 0)
+	s.SetVisibleFunc(nil, h)
+	s.UpdateVisible([]int{1, 2}, func() {}, h)
+	require.Len(t, events, 0)
+
+	require.Nil(t, s.Resize(0, 0, 200, 200, h))
+	require.Nil(t, s.Regrid(20, 20, h))
+	require.NotNil(t, s.Regrid(0, 0, h))
+	require.Len(t, events, 0)
+	st := s.State()
+	require.Equal(t, []int{200, 20}, []int{st.Config.Width, st.Config.GridW})
+	require.Len(t, st.Objs, 2)
+}
+
+func TestSafeAOI_Race(t *testing.T) {
+	const (
+		w, h    = 200, 200
+		num     = 200
+		workers = 8
+		loop    = 2000
+	)
+	a, err := NewAOIManager[int](w, h, 10, 10, WithSparse(), WithVisibility())
+	require.Nil(t, err)
+	s := NewSafeAOIManager(a)
+
+	// 不同goroutine的回调可能同时执行
+	var (
+		mu     sync.Mutex
+		events int
+	)
+	for id := 0; id < num; id++ {
+		s.Enter(id, rand.Intn(w), rand.Intn(h), TriggerAndObserver, nil, WithHandler(func(e *Event[int, int]) {
+			mu.Lock()
+			defer mu.Unlock()
+			events++
+		}))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		// 移动
+		go func(worker int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(worker)))
+			for j := 0; j < loop; j++ {
+				id := worker + r.Intn(num/workers)*workers
+				s.Move(id, r.Intn(w), r.Intn(h), func(event EventType, other int) {
+					s.Around(other)
+				})
+			}
+		}(i)
+		// 查询
+		go func() {
+			defer wg.Done()
+			for j := 0; j < loop; j++ {
+				s.Around(rand.Intn(num))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每个对象的视野和相邻格子一致
+	require.Positive(t, events)
+	for id := 0; id < num; id++ {
+		require.ElementsMatch(t, s.Around(id), s.VisibleTo(id))
+	}
+}
+
+func TestSafeAOI_CallerGoroutine(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewSafeAOIManager(a)
+	s.Enter(1, 15, 15, TriggerAndObserver, nil)
+
+	// 另一个goroutine的回调阻塞时, 自己的回调仍然在返回之前执行完
+	var (
+		blocked = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		s.Enter(2, 25, 15, TriggerAndObserver, func(event EventType, other int) {
+			close(blocked)
+			<-release
+		})
+	}()
+	<-blocked
+	var got []int
+	require.True(t, s.Enter(3, 15, 25, TriggerAndObserver, func(event EventType, other int) {
+		got = append(got, other)
+	}))
+	require.ElementsMatch(t, []int{1, 2}, got)
+	close(release)
+	<-done
+}
+
+func keys(m map[int]struct{}) []int {
+	ret := make([]int, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
+}
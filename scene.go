@@ -0,0 +1,226 @@
+package aoi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+actor模式的场景
+
+Scene在一个goroutine(Run)里独占AOIManager, 其他goroutine通过命令channel提交Enter, Leave, Move和Query,
+不需要加锁。产生的事件(和SetEventHandler收到的一致)发布到每个订阅者自己的channel。
+
+订阅者channel满时会阻塞场景, 订阅时要给足缓冲并及时读取。
+ctx取消或者调用Close后, Run会先执行完已经提交的命令, 再关闭所有订阅者的channel后返回。
+命令的ctx取消时, 还没有执行的命令不再执行, 已经开始执行的命令等它执行完再返回。
+*/
+
+// ErrSceneClosed 场景已经关闭
+var ErrSceneClosed = errors.New("scene closed")
+
+// Scene actor模式的场景
+type Scene[T ObjID, P Coord] struct {
+	m         *AOIManager[T, P]
+	cmds      chan func()                  // 命令
+	quit      chan struct{}                // Close
+	done      chan struct{}                // Run退出后关闭
+	closeOnce sync.Once                    // 只Close一次
+	subs      map[*Subscription[T, P]]bool // 订阅者, 只在Run里访问
+}
+
+// Subscription 订阅
+type Subscription[T ObjID, P Coord] struct {
+	s    *Scene[T, P]
+	c    chan Event[T, P]
+	quit chan struct{} // 取消订阅, 不再阻塞场景
+	once sync.Once
+}
+
+// C 事件channel, 场景关闭或者取消订阅后关闭
+func (sub *Subscription[T, P]) C() <-chan Event[T, P] {
+	return sub.c
+}
+
+// Unsubscribe 取消订阅
+func (sub *Subscription[T, P]) Unsubscribe() {
+	sub.once.Do(func() {
+		close(sub.quit)
+	})
+	_ = sub.s.do(context.Background(), func() {
+		if sub.s.subs[sub] {
+			delete(sub.s.subs, sub)
+			close(sub.c)
+		}
+	})
+}
+
+// NewScene 构造, m之后只能通过Scene访问
+// size 命令channel的缓冲
+func NewScene[T ObjID, P Coord](m *AOIManager[T, P], size int) *Scene[T, P] {
+	return &Scene[T, P]{
+		m:    m,
+		cmds: make(chan func(), size),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+		subs: make(map[*Subscription[T, P]]bool),
+	}
+}
+
+// Run 执行命令直到ctx取消或者Close, 只能调用一次
+func (s *Scene[T, P]) Run(ctx context.Context) error {
+	s.m.SetEventHandler(func(e *Event[T, P]) {
+		for sub := range s.subs {
+			select {
+			case sub.c <- *e:
+			case <-sub.quit:
+				// 已经取消订阅, 不再等Unsubscribe的命令
+				delete(s.subs, sub)
+				close(sub.c)
+			case <-ctx.Done():
+			case <-s.quit:
+			}
+		}
+	})
+	defer func() {
+		s.m.SetEventHandler(nil)
+		for sub := range s.subs {
+			close(sub.c)
+		}
+		s.subs = nil
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case cmd := <-s.cmds:
+			cmd()
+		case <-ctx.Done():
+			s.drain()
+			return ctx.Err()
+		case <-s.quit:
+			s.drain()
+			return nil
+		}
+	}
+}
+
+// drain 执行已经提交的命令
+func (s *Scene[T, P]) drain() {
+	for {
+		select {
+		case cmd := <-s.cmds:
+			cmd()
+		default:
+			return
+		}
+	}
+}
+
+// Close 关闭场景, 等待Run返回
+func (s *Scene[T, P]) Close() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+	})
+	<-s.done
+}
+
+// Done Run返回后关闭
+func (s *Scene[T, P]) Done() <-chan struct{} {
+	return s.done
+}
+
+// 命令的状态
+const (
+	cmdPending   int32 = iota // 已经提交, 还没有执行
+	cmdRunning                // 正在执行或者已经执行
+	cmdCancelled              // 调用方已经返回, 不再执行
+)
+
+// do 提交命令并等待执行完成
+// 返回nil时f已经执行完; 返回错误时f不会执行, 调用方不能读f的结果
+// ctx取消时如果f已经开始执行, 仍然等它执行完再返回nil
+func (s *Scene[T, P]) do(ctx context.Context, f func()) error {
+	var (
+		state    atomic.Int32
+		finished = make(chan struct{})
+	)
+	cmd := func() {
+		if !state.CompareAndSwap(cmdPending, cmdRunning) {
+			return
+		}
+		f()
+		close(finished)
+	}
+	// cancel 取消还没有执行的命令, 命令已经开始执行时等它执行完
+	cancel := func(err error) error {
+		if state.CompareAndSwap(cmdPending, cmdCancelled) {
+			return err
+		}
+		<-finished
+		return nil
+	}
+	select {
+	case s.cmds <- cmd:
+	case <-s.done:
+		return ErrSceneClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-finished:
+		return nil
+	case <-s.done:
+		// Run退出前会执行完已经提交的命令, 没有执行的是在退出之后提交的
+		return cancel(ErrSceneClosed)
+	case <-ctx.Done():
+		return cancel(ctx.Err())
+	}
+}
+
+// Subscribe 订阅事件
+// size channel的缓冲
+func (s *Scene[T, P]) Subscribe(ctx context.Context, size int) (*Subscription[T, P], error) {
+	sub := &Subscription[T, P]{s: s, c: make(chan Event[T, P], size), quit: make(chan struct{})}
+	// 返回错误时命令不会执行, 不会留下没有人读的订阅
+	if err := s.do(ctx, func() { s.subs[sub] = true }); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Enter 进入
+// 监听的类型参数不一致时返回false
+func (s *Scene[T, P]) Enter(ctx context.Context, id T, posX, posY P, ot ObjType, opts ...EnterOption) (bool, error) {
+	var ok bool
+	if err := s.do(ctx, func() { ok = s.m.Enter(id, posX, posY, ot, nil, opts...) }); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// Leave 离开
+func (s *Scene[T, P]) Leave(ctx context.Context, id T) (bool, error) {
+	var ok bool
+	if err := s.do(ctx, func() { ok = s.m.Leave(id, nil) }); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// Move 移动
+func (s *Scene[T, P]) Move(ctx context.Context, id T, toPosX, toPosY P) (bool, error) {
+	var ok bool
+	if err := s.do(ctx, func() { ok = s.m.Move(id, toPosX, toPosY, nil) }); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// Query 在场景的goroutine里执行f, f里不要保存m或者m返回的格子
+// 返回错误时f不会执行
+func (s *Scene[T, P]) Query(ctx context.Context, f func(m *AOIManager[T, P])) error {
+	return s.do(ctx, func() { f(s.m) })
+}
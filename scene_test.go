@@ -0,0 +1,174 @@
+package aoi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScene(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewScene(a, 16)
+	go s.Run(context.Background())
+
+	ctx := context.Background()
+	sub, err := s.Subscribe(ctx, 16)
+	require.Nil(t, err)
+
+	ok, err := s.Enter(ctx, 1, 15, 15, TriggerAndObserver)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = s.Enter(ctx, 1, 15, 15, TriggerAndObserver)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	s.Enter(ctx, 2, 25, 15, Trigger)
+	e := <-sub.C()
+	require.Equal(t, EnterView, e.Type)
+	require.Equal(t, 2, e.Trigger)
+	require.Equal(t, 1, e.Receiver)
+
+	s.Move(ctx, 2, 26, 16)
+	e = <-sub.C()
+	require.Equal(t, UpdateView, e.Type)
+	require.Equal(t, 26, e.TriggerX)
+
+	var n int
+	require.Nil(t, s.Query(ctx, func(m *AOIManager[int, int]) {
+		n = len(m.ObjGrid(1).ObjIDs())
+	}))
+	require.Equal(t, 1, n)
+
+	ok, err = s.Leave(ctx, 2)
+	require.Nil(t, err)
+	require.True(t, ok)
+	e = <-sub.C()
+	require.Equal(t, LeaveView, e.Type)
+
+	// 监听的类型不一致时返回false
+	ok, err = s.Enter(ctx, 3, 15, 15, Observer, WithHandler[string, int](func(*Event[string, int]) {}))
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = s.Enter(ctx, 3, 15, 15, Observer)
+	require.Nil(t, err)
+	require.True(t, ok)
+	e = <-sub.C()
+	require.Equal(t, EnterView, e.Type)
+	require.Equal(t, 3, e.Trigger)
+
+	// 取消订阅后channel关闭
+	sub.Unsubscribe()
+	_, more := <-sub.C()
+	require.False(t, more)
+
+	s.Close()
+	_, err = s.Enter(ctx, 3, 15, 15, Trigger)
+	require.Equal(t, ErrSceneClosed, err)
+	_, err = s.Subscribe(ctx, 1)
+	require.Equal(t, ErrSceneClosed, err)
+	sub.Unsubscribe()
+}
+
+func TestScene_Shutdown(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewScene(a, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	// 慢订阅者不读, 场景阻塞在发布事件上, 取消ctx可以退出
+	sub, err := s.Subscribe(context.Background(), 0)
+	require.Nil(t, err)
+	s.Enter(context.Background(), 1, 15, 15, Observer)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := s.Enter(context.Background(), 2, 15, 15, Trigger)
+		require.Nil(t, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// 命令的ctx超时
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+	_, err = s.Move(timeout, 1, 16, 16)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-runErr)
+	wg.Wait()
+	for range sub.C() {
+	}
+	<-s.Done()
+	s.Close()
+}
+
+func TestScene_SubscribeTimeout(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewScene(a, 16)
+
+	// Run之前订阅, 命令已经提交但是ctx超时, 之后的事件不能阻塞场景
+	timeout, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.Subscribe(timeout, 0)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	go s.Run(context.Background())
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ok, err := s.Enter(ctx, 1, 15, 15, TriggerAndObserver)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = s.Enter(ctx, 2, 16, 16, TriggerAndObserver)
+	require.Nil(t, err)
+	require.True(t, ok)
+}
+
+func TestScene_Cancel(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	s := NewScene(a, 16)
+	go s.Run(context.Background())
+	defer s.Close()
+
+	ok, err := s.Enter(context.Background(), 1, 15, 15, TriggerAndObserver)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// ctx已经取消, 返回错误的命令不会执行, 结果也不会被场景的goroutine写
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var failed int
+	for i := 0; i < 1000; i++ {
+		ok, err := s.Move(cancelled, 1, 16+i%10, 16)
+		if err != nil {
+			require.False(t, ok)
+			failed++
+			continue
+		}
+		require.True(t, ok)
+	}
+	require.Positive(t, failed)
+
+	// 返回错误的Query不会在之后执行f
+	var queried, succeeded int
+	for i := 0; i < 1000; i++ {
+		if s.Query(cancelled, func(m *AOIManager[int, int]) { queried++ }) == nil {
+			succeeded++
+		}
+	}
+	var n int
+	require.Nil(t, s.Query(context.Background(), func(m *AOIManager[int, int]) { n = queried }))
+	require.Equal(t, succeeded, n)
+}
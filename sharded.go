@@ -0,0 +1,286 @@
+package aoi
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+/*
+分片aoi
+
+ShardedAOIManager把地图按列切成若干竖条, 每个分片有自己的锁和AOIManager,
+不同分片内的移动可以在多个goroutine里并行处理。
+
+每个分片除了自己的列, 两边再各多保存2*radius列的对象(边界的影子对象),
+所以分片内以及离分片边界radius列以内的对象的相邻格子都是完整的, 事件由一个分片算出:
+1. 移动前后都在某个分片radius列的范围内: 由这个分片计算Move的事件
+2. 否则移动前后的相邻格子没有交集, 由原来的分片计算LeaveView, 新的分片计算EnterView
+其他分片只同步影子对象, 不产生事件。
+
+一次操作会锁住所有保存了这个对象的分片, 按分片顺序加锁, 不会死锁。
+只有靠近边界的操作才会锁多个分片, 分片越宽并行度越好。
+回调在锁内执行, 保证同一对对象的事件顺序正确, 回调里不能再调用ShardedAOIManager。
+
+***注意***
+同一个对象的操作不要并发调用。
+Clear可以和其他操作并发调用, Enter和Move加锁之后才登记或者修改对象, 不会在分片里留下已经清除的对象。
+不支持六边形格子, 环形地图, 格子切换的滞后和可见集合(WithVisibility)。
+影子对象和原对象使用同样的进入选项, 对象监听(WithListener, WithHandler)会在每个分片重复通知, 所以也不支持。
+*/
+
+var _ Manager[int, int] = (*ShardedAOIManager[int, int])(nil)
+
+// shardObj 分片aoi中的对象
+type shardObj[P Coord] struct {
+	x, y P
+	ot   ObjType
+	col  int           // 所在的列
+	opts []EnterOption // 影子对象进入时使用
+}
+
+// shard 分片
+type shard[T ObjID, P Coord] struct {
+	mu     sync.Mutex
+	m      *AOIManager[T, P]
+	lo, hi int // 保存的列范围[lo, hi), 包含两边的影子
+}
+
+// ShardedAOIManager 分片aoi管理器
+type ShardedAOIManager[T ObjID, P Coord] struct {
+	shards []*shard[T, P]
+	cols   int      // 每个分片的列数, 最后一个分片包含剩下的列
+	radius int      // 相邻半径(格子数)
+	objs   sync.Map // T -> *shardObj[P]
+}
+
+// NewShardedAOIManager 构造
+// shardNum 分片数, 每个分片至少要有2*radius+1列
+func NewShardedAOIManager[T ObjID, P Coord](width, height P, gridW, gridH P, shardNum int, opts ...Option) (*ShardedAOIManager[T, P], error) {
+	o := options{radius: DefaultRadius}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hex || o.wrap || o.hysteresis > 0 || o.visibility {
+		return nil, fmt.Errorf("sharded manager does not support hex, wrap, hysteresis or visibility")
+	}
+	if shardNum <= 0 {
+		return nil, fmt.Errorf("shardNum should be greater than 0")
+	}
+	// 每个分片都是覆盖整个地图的稀疏AOIManager, 坐标和格子id完全一致
+	opts = append(opts[:len(opts):len(opts)], WithSparse())
+	m := &ShardedAOIManager[T, P]{radius: o.radius}
+	for i := 0; i < shardNum; i++ {
+		a, err := NewAOIManager[T, P](width, height, gridW, gridH, opts...)
+		if err != nil {
+			return nil, err
+		}
+		m.shards = append(m.shards, &shard[T, P]{m: a})
+	}
+	col := m.shards[0].m.col
+	m.cols = col / shardNum
+	if m.cols < 2*o.radius+1 {
+		return nil, fmt.Errorf("each shard should have at least %d columns", 2*o.radius+1)
+	}
+	for i, s := range m.shards {
+		s.lo, s.hi = i*m.cols-2*o.radius, (i+1)*m.cols+2*o.radius
+		if i == shardNum-1 {
+			s.hi = col + 2*o.radius
+		}
+	}
+	return m, nil
+}
+
+// Enter 进入
+// eventType 只会是EnterView
+// 传入WithListener或者WithHandler时返回false
+func (m *ShardedAOIManager[T, P]) Enter(id T, posX, posY P, ot ObjType, cb EventCallback[T], opts ...EnterOption) bool {
+	var eo enterOptions
+	for _, opt := range opts {
+		opt(&eo)
+	}
+	if eo.listener != nil {
+		return false
+	}
+	o := &shardObj[P]{x: posX, y: posY, ot: ot, col: m.posCol(posX, posY), opts: opts}
+	shards := m.shardsAt(nil, o.col)
+	// 加锁之后再登记, 并发的Clear不会漏掉已经登记的对象
+	m.lock(shards)
+	defer m.unlock(shards)
+	if _, loaded := m.objs.LoadOrStore(id, o); loaded {
+		return false
+	}
+	for _, i := range shards {
+		var _cb EventCallback[T]
+		if i == m.owner(o.col) {
+			_cb = cb
+		}
+		m.shards[i].m.Enter(id, posX, posY, ot, _cb, opts...)
+	}
+	return true
+}
+
+// Leave 离开
+// event 只会是LeaveView
+func (m *ShardedAOIManager[T, P]) Leave(id T, cb EventCallback[T]) bool {
+	v, ok := m.objs.LoadAndDelete(id)
+	if !ok {
+		return false
+	}
+	var (
+		o      = v.(*shardObj[P])
+		shards = m.shardsAt(nil, o.col)
+	)
+	m.lock(shards)
+	defer m.unlock(shards)
+	for _, i := range shards {
+		var _cb EventCallback[T]
+		if i == m.owner(o.col) {
+			_cb = cb
+		}
+		m.shards[i].m.Leave(id, _cb)
+	}
+	return true
+}
+
+// Move 移动
+func (m *ShardedAOIManager[T, P]) Move(id T, toPosX, toPosY P, cb EventCallback[T]) bool {
+	v, ok := m.objs.Load(id)
+	if !ok {
+		return false
+	}
+	var (
+		o        = v.(*shardObj[P])
+		fromCol  = o.col
+		toCol    = m.posCol(toPosX, toPosY)
+		shards   = m.shardsAt(m.shardsAt(nil, fromCol), toCol)
+		computed = -1
+	)
+	m.lock(shards)
+	defer m.unlock(shards)
+	// 加锁之前并发的Clear可能已经清除了对象, 不能再放回分片
+	if cur, ok := m.objs.Load(id); !ok || cur != v {
+		return false
+	}
+	o.x, o.y, o.col = toPosX, toPosY, toCol
+	// 1. 一个分片就能算出所有事件
+	for _, i := range []int{m.owner(fromCol), m.owner(toCol)} {
+		if m.covers(i, fromCol) && m.covers(i, toCol) {
+			m.shards[i].m.Move(id, toPosX, toPosY, cb)
+			computed = i
+			break
+		}
+	}
+	// 2. 前后相邻格子没有交集
+	if computed < 0 {
+		from, to := m.shards[m.owner(fromCol)].m, m.shards[m.owner(toCol)].m
+		from.Leave(id, cb)
+		to.Leave(id, nil)
+		to.Enter(id, toPosX, toPosY, o.ot, cb, o.opts...)
+	}
+	// 同步影子对象
+	for _, i := range shards {
+		if i == computed {
+			continue
+		}
+		a := m.shards[i].m
+		_, has := a.objs[id]
+		want := toCol >= m.shards[i].lo && toCol < m.shards[i].hi
+		switch {
+		case want && has:
+			a.Move(id, toPosX, toPosY, nil)
+		case want:
+			a.Enter(id, toPosX, toPosY, o.ot, nil, o.opts...)
+		case has:
+			a.Leave(id, nil)
+		}
+	}
+	return true
+}
+
+// Clear 清空
+func (m *ShardedAOIManager[T, P]) Clear() {
+	shards := make([]int, len(m.shards))
+	for i := range shards {
+		shards[i] = i
+	}
+	m.lock(shards)
+	defer m.unlock(shards)
+	for _, s := range m.shards {
+		s.m.Clear()
+	}
+	m.objs.Range(func(key, _ any) bool {
+		m.objs.Delete(key)
+		return true
+	})
+}
+
+// String 格式化输出, 每个分片只输出自己的对象
+func (m *ShardedAOIManager[T, P]) String() string {
+	str := ""
+	for i, s := range m.shards {
+		s.mu.Lock()
+		str += fmt.Sprintf("shard%d:", i)
+		for _, g := range s.m.AllGrids() {
+			if _, col := g.RowCol(); m.owner(col) == i {
+				str += fmt.Sprintf(" %s%d", g, len(g.ObjIDs()))
+			}
+		}
+		str += "\n"
+		s.mu.Unlock()
+	}
+	return str
+}
+
+// posCol 坐标所在的列
+func (m *ShardedAOIManager[T, P]) posCol(posX, posY P) int {
+	a := m.shards[0].m
+	_, _, col := a.gridLayerRowCol(a.posAtGridIndex(posX, posY, 0))
+	return col
+}
+
+// owner 列所属的分片
+func (m *ShardedAOIManager[T, P]) owner(col int) int {
+	i := col / m.cols
+	if i >= len(m.shards) {
+		i = len(m.shards) - 1
+	}
+	return i
+}
+
+// covers 分片i是否有col列的完整相邻格子, 即col在分片的radius列范围内
+func (m *ShardedAOIManager[T, P]) covers(i, col int) bool {
+	s := m.shards[i]
+	return col >= s.lo+m.radius && col < s.hi-m.radius
+}
+
+// shardsAt 把保存了col列的分片加到shards, 保持有序不重复
+// 影子只有2*radius列, 不会超过相邻的分片
+func (m *ShardedAOIManager[T, P]) shardsAt(shards []int, col int) []int {
+	owner := m.owner(col)
+	for i := owner - 1; i <= owner+1; i++ {
+		if i < 0 || i >= len(m.shards) || col < m.shards[i].lo || col >= m.shards[i].hi {
+			continue
+		}
+		if n := sort.SearchInts(shards, i); n == len(shards) || shards[n] != i {
+			shards = append(shards, 0)
+			copy(shards[n+1:], shards[n:])
+			shards[n] = i
+		}
+	}
+	return shards
+}
+
+// lock 按顺序加锁
+func (m *ShardedAOIManager[T, P]) lock(shards []int) {
+	for _, i := range shards {
+		m.shards[i].mu.Lock()
+	}
+}
+
+// unlock 解锁
+func (m *ShardedAOIManager[T, P]) unlock(shards []int) {
+	for _, i := range shards {
+		m.shards[i].mu.Unlock()
+	}
+}
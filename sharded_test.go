@@ -0,0 +1,245 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharded_Init(t *testing.T) {
+	_, err := NewShardedAOIManager[int](100, 100, 10, 10, 0)
+	require.NotNil(t, err)
+	_, err = NewShardedAOIManager[int](100, 100, 10, 10, 4)
+	require.NotNil(t, err)
+	_, err = NewShardedAOIManager[int](100, 100, 10, 10, 2, WithHex())
+	require.NotNil(t, err)
+	_, err = NewShardedAOIManager[int](100, 100, 10, 10, 2, WithVisibility())
+	require.NotNil(t, err)
+	m, err := NewShardedAOIManager[int](100, 100, 10, 10, 3)
+	require.Nil(t, err)
+	require.Equal(t, 3, m.cols)
+	require.Equal(t, []int{0}, m.shardsAt(nil, 0))
+	require.Equal(t, []int{0, 1}, m.shardsAt(nil, 2))
+	require.Equal(t, []int{1, 2}, m.shardsAt(nil, 7))
+	require.Equal(t, 2, m.owner(9))
+
+	// 影子对象会让监听在每个分片重复通知
+	require.False(t, m.Enter(1, 25, 25, Observer, nil, WithHandler(func(*Event[int, int]) {})))
+	require.False(t, m.Leave(1, nil))
+}
+
+// 和单个AOIManager的事件完全一致
+func TestSharded_Events(t *testing.T) {
+	const (
+		w, h = 300, 300
+		num  = 200
+	)
+	for _, radius := range []int{1, 2} {
+		single, err := NewAOIManager[int](w, h, 10, 10, WithRadius(radius))
+		require.Nil(t, err)
+		sharded, err := NewShardedAOIManager[int](w, h, 10, 10, 5, WithRadius(radius))
+		require.Nil(t, err)
+
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			x, y, ot := rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))]
+			opt := WithViewRadius(float64(rand.Intn(30)))
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			require.True(t, single.Enter(id, x, y, ot, r1.callFunc(), opt))
+			require.True(t, sharded.Enter(id, x, y, ot, r2.callFunc(), opt))
+			r1.requireEqual(t, r2, "enter")
+		}
+		require.False(t, sharded.Enter(0, 0, 0, Trigger, nil))
+
+		for i := 0; i < 5000; i++ {
+			id := rand.Intn(num)
+			x, y := rand.Intn(w+40)-20, rand.Intn(h+40)-20
+			if i%2 == 0 {
+				o := single.objs[id]
+				x, y = o.x-30+rand.Intn(60), o.y-30+rand.Intn(60)
+			}
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			single.Move(id, x, y, r1.callFunc())
+			sharded.Move(id, x, y, r2.callFunc())
+			r1.requireEqual(t, r2, fmt.Sprint("move ", i))
+		}
+
+		for id := 0; id < num; id++ {
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			single.Leave(id, r1.callFunc())
+			require.True(t, sharded.Leave(id, r2.callFunc()))
+			r1.requireEqual(t, r2, "leave")
+		}
+		require.False(t, sharded.Leave(0, nil))
+		require.False(t, sharded.Move(0, 0, 0, nil))
+		for _, s := range sharded.shards {
+			require.Len(t, s.m.objs, 0)
+			require.Len(t, s.m.sparse, 0)
+		}
+	}
+}
+
+func TestSharded_Race(t *testing.T) {
+	const (
+		w, h    = 300, 300
+		num     = 400
+		workers = 8
+		loop    = 2000
+	)
+	m, err := NewShardedAOIManager[int](w, h, 10, 10, 4)
+	require.Nil(t, err)
+
+	var (
+		mu       sync.Mutex
+		seeLists = make([]map[int]struct{}, num)
+		pos      = make([][2]int, num)
+	)
+	// 和demo一样, 在回调里维护双方的视野
+	cb := func(id int) EventCallback[int] {
+		return func(event EventType, other int) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch event {
+			case EnterView:
+				seeLists[id][other] = struct{}{}
+				seeLists[other][id] = struct{}{}
+			case LeaveView:
+				delete(seeLists[id], other)
+				delete(seeLists[other], id)
+			}
+		}
+	}
+	for id := 0; id < num; id++ {
+		seeLists[id] = map[int]struct{}{}
+		pos[id] = [2]int{rand.Intn(w), rand.Intn(h)}
+		m.Enter(id, pos[id][0], pos[id][1], TriggerAndObserver, cb(id))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(worker)))
+			for j := 0; j < loop; j++ {
+				id := worker + r.Intn(num/workers)*workers
+				p := &pos[id]
+				p[0], p[1] = p[0]-20+r.Intn(40), p[1]-20+r.Intn(40)
+				m.Move(id, p[0], p[1], cb(id))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	single, err := NewAOIManager[int](w, h, 10, 10)
+	require.Nil(t, err)
+	for id := 0; id < num; id++ {
+		single.Enter(id, pos[id][0], pos[id][1], TriggerAndObserver, nil)
+	}
+	for id := 0; id < num; id++ {
+		expect := map[int]struct{}{}
+		single.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+			if other != id {
+				expect[other] = struct{}{}
+			}
+			return true
+		})
+		require.Equal(t, expect, seeLists[id], id)
+	}
+}
+
+// 移动时并发Clear, 分片里不能留下已经清除的对象
+func TestSharded_ClearWhileMoving(t *testing.T) {
+	const (
+		w, h    = 300, 300
+		num     = 400
+		workers = 8
+		loop    = 2000
+	)
+	m, err := NewShardedAOIManager[int](w, h, 10, 10, 4)
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(worker)))
+			for j := 0; j < loop; j++ {
+				id := worker + r.Intn(num/workers)*workers
+				x, y := r.Intn(w), r.Intn(h)
+				if !m.Move(id, x, y, nil) {
+					m.Enter(id, x, y, TriggerAndObserver, nil)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 100; i++ {
+		m.Clear()
+		runtime.Gosched()
+	}
+	wg.Wait()
+
+	for i, s := range m.shards {
+		for id, o := range s.m.objs {
+			v, ok := m.objs.Load(id)
+			require.True(t, ok, "shard %d ghost %d", i, id)
+			so := v.(*shardObj[int])
+			require.Equal(t, [2]int{so.x, so.y}, [2]int{o.x, o.y}, id)
+		}
+	}
+	m.objs.Range(func(key, v any) bool {
+		_, ok := m.shards[m.owner(v.(*shardObj[int]).col)].m.objs[key.(int)]
+		require.True(t, ok, key)
+		return true
+	})
+}
+
+// 和BenchmarkAOI_Move同样的地图和对象数, aoi是单个AOIManager在一个goroutine里的基准
+func BenchmarkSharded_Move(b *testing.B) {
+	const (
+		w   = 10000
+		h   = 10000
+		obj = 1000000
+	)
+	pos := make([][2]int, obj)
+	for i := range pos {
+		pos[i] = [2]int{rand.Int() % w, rand.Int() % h}
+	}
+	// run 用workers个goroutine移动, 每个worker只移动id%workers==n的对象
+	run := func(b *testing.B, m Manager[int, int], workers int) {
+		for i := 0; i < obj; i++ {
+			m.Enter(i, pos[i][0], pos[i][1], TriggerAndObserver, nil)
+		}
+		cb := func(event EventType, other int) {}
+		b.ReportAllocs()
+		b.ResetTimer()
+		var wg sync.WaitGroup
+		for n := 0; n < workers; n++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(n)))
+				for i := n; i < b.N; i += workers {
+					p := pos[i%obj]
+					_ = m.Move(i%obj, p[0]-20+r.Intn(40), p[1]-20+r.Intn(40), cb)
+				}
+			}(n)
+		}
+		wg.Wait()
+	}
+	b.Run("aoi", func(b *testing.B) {
+		m, _ := NewAOIManager[int](w, h, 10, 10)
+		run(b, m, 1)
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprint("workers", workers), func(b *testing.B) {
+			m, _ := NewShardedAOIManager[int](w, h, 10, 10, workers)
+			run(b, m, workers)
+		})
+	}
+}
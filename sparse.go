@@ -0,0 +1,99 @@
+package aoi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+稀疏模式
+
+默认模式在构造时就分配了所有col*row个格子, 地图很大格子很小时非常占内存。
+稀疏模式下格子在第一个对象进入时才分配, 并和周围已分配的格子互相关联,
+格子里的对象全部离开后回收, 同时解除和周围格子的关联。
+没有分配的格子里没有对象, 不影响事件的计算, 所以事件规则和默认模式完全一致。
+*/
+
+// WithSparse 稀疏模式, 只在有对象的地方分配格子
+// 适合很大但是对象很稀疏的地图
+func WithSparse() Option {
+	return func(o *options) {
+		o.sparse = true
+	}
+}
+
+// grid 格子id对应的格子, 稀疏模式下没有分配返回nil
+func (m *AOIManager[ObjID, P]) grid(id int) *Grid[ObjID, P] {
+	if m.sparse != nil {
+		return m.sparse[id]
+	}
+	return m.grids[id]
+}
+
+// acquireGrid 坐标所在的格子, 稀疏模式下没有分配就分配
+func (m *AOIManager[ObjID, P]) acquireGrid(posX, posY, posZ P) *Grid[ObjID, P] {
+	return m.acquireGridIndex(m.posAtGridIndex(posX, posY, posZ))
+}
+
+// acquireGridIndex 格子id对应的格子, 稀疏模式下没有分配就分配
+func (m *AOIManager[ObjID, P]) acquireGridIndex(idx int) *Grid[ObjID, P] {
+	if m.sparse == nil {
+		return m.grids[idx]
+	}
+	if g, ok := m.sparse[idx]; ok {
+		return g
+	}
+
+	g := m.newGridAt(m.gridLayerRowCol(idx))
+	m.sparse[idx] = g
+	m.foreachSurround(g, func(idx int) {
+		surroundGrid, ok := m.sparse[idx]
+		if !ok {
+			return
+		}
+		g.addSurroundGrid(surroundGrid)
+		if surroundGrid != g {
+			surroundGrid.addSurroundGrid(g)
+		}
+	})
+	return g
+}
+
+// releaseGrid 稀疏模式下回收没有对象的格子
+func (m *AOIManager[ObjID, P]) releaseGrid(g *Grid[ObjID, P]) {
+	if m.sparse == nil || len(g.objs) > 0 {
+		return
+	}
+	// Clear之后g可能已经不在了
+	if m.sparse[g.id] != g {
+		return
+	}
+	for _, sg := range g.surroundGrids {
+		if sg != g {
+			sg.delSurroundGrid(g)
+		}
+	}
+	delete(m.sparse, g.id)
+}
+
+// sparseGrids 已分配的格子, 按格子id排序
+func (m *AOIManager[ObjID, P]) sparseGrids() []*Grid[ObjID, P] {
+	grids := make([]*Grid[ObjID, P], 0, len(m.sparse))
+	for _, g := range m.sparse {
+		grids = append(grids, g)
+	}
+	sort.Slice(grids, func(i, j int) bool {
+		return grids[i].id < grids[j].id
+	})
+	return grids
+}
+
+// sparseString 稀疏模式格式化输出, 只输出已分配的格子
+func (m *AOIManager[ObjID, P]) sparseString() string {
+	var sb strings.Builder
+	for _, g := range m.sparseGrids() {
+		sb.WriteString(fmt.Sprintf("%10s %d\n", g, len(g.objs)))
+	}
+	return sb.String()
+}
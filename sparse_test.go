@@ -0,0 +1,104 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder map[EventType][]int
+
+func (r eventRecorder) callFunc() EventCallback[int] {
+	return func(event EventType, other int) {
+		r[event] = append(r[event], other)
+	}
+}
+
+func (r eventRecorder) requireEqual(t *testing.T, other eventRecorder, msg string) {
+	for _, e := range []EventType{EnterView, LeaveView, UpdateView} {
+		require.ElementsMatch(t, r[e], other[e], msg)
+	}
+}
+
+func TestAOI_Sparse(t *testing.T) {
+	const (
+		w, h = 300, 300
+		num  = 200
+	)
+	for _, radius := range []int{1, 2} {
+		dense, err := NewAOIManager[int](w, h, 10, 10, WithRadius(radius))
+		require.Nil(t, err)
+		sparse, err := NewAOIManager[int](w, h, 10, 10, WithRadius(radius), WithSparse())
+		require.Nil(t, err)
+		require.Len(t, sparse.AllGrids(), 0)
+		require.Nil(t, sparse.PosAtGrid(0, 0))
+
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			x, y, ot := rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))]
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			dense.Enter(id, x, y, ot, r1.callFunc())
+			sparse.Enter(id, x, y, ot, r2.callFunc())
+			r1.requireEqual(t, r2, "enter")
+		}
+		for i := 0; i < 2000; i++ {
+			id := rand.Intn(num)
+			x, y := rand.Intn(w), rand.Intn(h)
+			if i%2 == 0 {
+				g := dense.ObjGrid(id)
+				x, y = g.minX-15+rand.Intn(30), g.minY-15+rand.Intn(30)
+			}
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			dense.Move(id, x, y, r1.callFunc())
+			sparse.Move(id, x, y, r2.callFunc())
+			r1.requireEqual(t, r2, fmt.Sprint("move ", i))
+			require.Equal(t, dense.ObjGrid(id).ID(), sparse.ObjGrid(id).ID())
+		}
+
+		for _, g := range sparse.AllGrids() {
+			require.NotEmpty(t, g.ObjIDs())
+			require.Same(t, g, sparse.PosAtGrid(g.minX, g.minY))
+			var surround []int
+			for _, sg := range dense.grids[g.id].SurroundGrids() {
+				if len(sg.ObjIDs()) > 0 {
+					surround = append(surround, sg.id)
+				}
+			}
+			var sparseSurround []int
+			for _, sg := range g.SurroundGrids() {
+				sparseSurround = append(sparseSurround, sg.id)
+			}
+			require.ElementsMatch(t, surround, sparseSurround)
+		}
+
+		for id := 0; id < num; id++ {
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			dense.Leave(id, r1.callFunc())
+			sparse.Leave(id, r2.callFunc())
+			r1.requireEqual(t, r2, "leave")
+		}
+		require.Len(t, sparse.AllGrids(), 0)
+	}
+}
+
+func TestAOI_SparseHugeMap(t *testing.T) {
+	a, err := NewAOIManager[int](100000, 100000, 1, 1, WithSparse())
+	require.Nil(t, err)
+
+	a.Enter(1, 50000, 50000, TriggerAndObserver, nil)
+	_shouldCall := testSet{1: {}}
+	a.Enter(2, 50001, 50001, TriggerAndObserver, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+	require.Len(t, a.AllGrids(), 2)
+
+	_shouldCall = testSet{1: {}}
+	a.Move(2, 50003, 50003, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+	require.Len(t, a.AllGrids(), 2)
+
+	a.Clear()
+	require.Len(t, a.AllGrids(), 0)
+	require.Equal(t, "", a.String())
+}
@@ -0,0 +1,224 @@
+package aoi
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"slices"
+)
+
+/*
+快照和恢复
+
+State保存管理器的配置和所有对象的坐标, 类型, 可见半径, 层和所在的格子,
+可以编码成带版本号的二进制(gob)或者json, 用来休眠空闲的副本或者在进程之间迁移场景:
+
+	data, err := m.State().MarshalBinary() // 或者 json.Marshal(m.State())
+	...
+	var s aoi.State[int, int]
+	err = s.UnmarshalBinary(data) // 或者 json.Unmarshal(data, &s)
+	m, err = aoi.Restore(&s)
+
+Restore不通知任何事件, 开启WithVisibility时重新计算可见集合。
+对象的监听, EventHandler和SetVisibleFunc设置的可见规则都是函数, 不会保存, 恢复后需要重新设置。
+*/
+
+// StateVersion 快照格式的版本
+const StateVersion = 1
+
+// stateMagic 二进制快照的文件头
+var stateMagic = [4]byte{'A', 'O', 'I', 'S'}
+
+// StateConfig 管理器的配置
+type StateConfig[P Coord] struct {
+	MinX       P       `json:"min_x"`
+	MinY       P       `json:"min_y"`
+	MinZ       P       `json:"min_z"`
+	Width      P       `json:"width"`
+	Height     P       `json:"height"`
+	Depth      P       `json:"depth"` // 2d时为1
+	GridW      P       `json:"grid_w"`
+	GridH      P       `json:"grid_h"`
+	GridD      P       `json:"grid_d"` // 2d时为1
+	Radius     int     `json:"radius"`
+	Sparse     bool    `json:"sparse,omitempty"`
+	Hex        bool    `json:"hex,omitempty"`
+	Wrap       bool    `json:"wrap,omitempty"`
+	Visibility bool    `json:"visibility,omitempty"`
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+}
+
+// ObjState 对象的状态
+type ObjState[T ObjID, P Coord] struct {
+	ID         T       `json:"id"`
+	X          P       `json:"x"`
+	Y          P       `json:"y"`
+	Z          P       `json:"z,omitempty"`
+	Type       ObjType `json:"type"`
+	ViewRadius float64 `json:"view_radius,omitempty"`
+	Layer      uint64  `json:"layer"`
+	Sight      uint64  `json:"sight"`
+	Grid       int     `json:"grid"` // 所在的格子, 开启滞后时可能和坐标所在的格子不一样
+}
+
+// State 管理器的快照
+type State[T ObjID, P Coord] struct {
+	Version int              `json:"version"`
+	Config  StateConfig[P]   `json:"config"`
+	Objs    []ObjState[T, P] `json:"objs"`
+}
+
+// gobState 没有MarshalBinary方法的State, 避免gob递归调用
+type gobState[T ObjID, P Coord] State[T, P]
+
+// State 当前状态的快照, 对象按所在的格子和id排序, 相同的状态得到相同的快照
+func (m *AOIManager[ObjID, P]) State() *State[ObjID, P] {
+	s := &State[ObjID, P]{
+		Version: StateVersion,
+		Config:  m.config(),
+		Objs:    make([]ObjState[ObjID, P], 0, len(m.objs)),
+	}
+	for _, g := range m.AllGrids() {
+		for id := range g.objs {
+			o := m.objs[id]
+			s.Objs = append(s.Objs, ObjState[ObjID, P]{
+				ID: id,
+				X:  o.x, Y: o.y, Z: o.z,
+				Type:       o.ot,
+				ViewRadius: o.viewRadius,
+				Layer:      o.layer,
+				Sight:      o.sight,
+				Grid:       g.id,
+			})
+		}
+	}
+	slices.SortFunc(s.Objs, func(a, b ObjState[ObjID, P]) int {
+		if a.Grid != b.Grid {
+			return cmp.Compare(a.Grid, b.Grid)
+		}
+		return compareID(a.ID, b.ID)
+	})
+	return s
+}
+
+// compareID 比较两个id, 整数和字符串按值比较, 其他类型按fmt.Sprint的结果比较
+func compareID[T ObjID](a, b T) int {
+	switch x := any(a).(type) {
+	case int:
+		return cmp.Compare(x, any(b).(int))
+	case int32:
+		return cmp.Compare(x, any(b).(int32))
+	case int64:
+		return cmp.Compare(x, any(b).(int64))
+	case uint32:
+		return cmp.Compare(x, any(b).(uint32))
+	case uint64:
+		return cmp.Compare(x, any(b).(uint64))
+	case string:
+		return cmp.Compare(x, any(b).(string))
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// Restore 从快照恢复管理器, 不通知任何事件
+func Restore[T ObjID, P Coord](s *State[T, P]) (*AOIManager[T, P], error) {
+	if s.Version != StateVersion {
+		return nil, fmt.Errorf("unsupported state version %d", s.Version)
+	}
+	c := s.Config
+	m, err := newAOIManager[T, P](c.MinX, c.MinY, c.MinZ, c.Width, c.Height, c.Depth, c.GridW, c.GridH, c.GridD, c.options()...)
+	if err != nil {
+		return nil, err
+	}
+	gridNum := m.layer * m.row * m.col
+	for _, st := range s.Objs {
+		if _, ok := m.objs[st.ID]; ok {
+			return nil, fmt.Errorf("duplicate obj %v", st.ID)
+		}
+		if st.Grid < 0 || st.Grid >= gridNum {
+			return nil, fmt.Errorf("obj %v grid %d out of range", st.ID, st.Grid)
+		}
+		g := m.acquireGridIndex(st.Grid)
+		if g.id != m.posAtGridIndex(st.X, st.Y, st.Z) && !(m.hysteresis > 0 && m.inHysteresis(g, st.X, st.Y, st.Z)) {
+			return nil, fmt.Errorf("obj %v at (%v,%v,%v) is not in grid %d", st.ID, st.X, st.Y, st.Z, st.Grid)
+		}
+		m.insert(&obj[T, P]{
+			id: st.ID,
+			x:  st.X, y: st.Y, z: st.Z,
+			ot:         st.Type,
+			viewRadius: st.ViewRadius,
+			layer:      st.Layer,
+			sight:      st.Sight,
+		}, g)
+	}
+	if m.visibility {
+		ids := make(map[T]int, len(m.objs))
+		for id := range m.objs {
+			ids[id] = 0
+		}
+		for e := range m.seeEdges(ids) {
+			m.updateVisible(EnterView, e.observer, m.objs[e.observer], e.target, m.objs[e.target])
+		}
+	}
+	return m, nil
+}
+
+// config 当前的配置
+func (m *AOIManager[ObjID, P]) config() StateConfig[P] {
+	return StateConfig[P]{
+		MinX: m.minX, MinY: m.minY, MinZ: m.minZ,
+		Width: m.maxX - m.minX, Height: m.maxY - m.minY, Depth: m.maxZ - m.minZ,
+		GridW: m.gridW, GridH: m.gridH, GridD: m.gridD,
+		Radius:     m.radius,
+		Sparse:     m.sparse != nil,
+		Hex:        m.hex,
+		Wrap:       m.wrap,
+		Visibility: m.visibility,
+		Hysteresis: m.hysteresis,
+	}
+}
+
+// options 配置对应的构造选项
+func (c *StateConfig[P]) options() []Option {
+	opts := []Option{WithRadius(c.Radius), WithHysteresis(c.Hysteresis)}
+	if c.Sparse {
+		opts = append(opts, WithSparse())
+	}
+	if c.Hex {
+		opts = append(opts, WithHex())
+	}
+	if c.Wrap {
+		opts = append(opts, WithWrap())
+	}
+	if c.Visibility {
+		opts = append(opts, WithVisibility())
+	}
+	return opts
+}
+
+// MarshalBinary 编码成二进制, 文件头和版本号后面是gob
+func (s *State[T, P]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(stateMagic[:])
+	if err := binary.Write(&buf, binary.BigEndian, uint16(s.Version)); err != nil {
+		return nil, err
+	}
+	if err := gob.NewEncoder(&buf).Encode((*gobState[T, P])(s)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 从MarshalBinary的结果解码
+func (s *State[T, P]) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stateMagic)+2 || !bytes.Equal(data[:len(stateMagic)], stateMagic[:]) {
+		return fmt.Errorf("invalid state header")
+	}
+	data = data[len(stateMagic):]
+	if version := int(binary.BigEndian.Uint16(data)); version != StateVersion {
+		return fmt.Errorf("unsupported state version %d", version)
+	}
+	return gob.NewDecoder(bytes.NewReader(data[2:])).Decode((*gobState[T, P])(s))
+}
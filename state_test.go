@@ -0,0 +1,165 @@
+package aoi
+
+import (
+	"encoding/json"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireSameState 两个管理器的格子和对象完全一致
+func requireSameState[P Coord](t *testing.T, a, b *AOIManager[int, P], msg string) {
+	require.Equal(t, a.String(), b.String(), msg)
+	require.Equal(t, len(a.AllGrids()), len(b.AllGrids()), msg)
+	for i, g := range a.AllGrids() {
+		other := b.AllGrids()[i]
+		require.Equal(t, g.ID(), other.ID(), msg)
+		require.ElementsMatch(t, keys(g.ObjIDs()), keys(other.ObjIDs()), msg)
+		require.ElementsMatch(t, keys(g.ObserverIDs()), keys(other.ObserverIDs()), msg)
+		require.Equal(t, len(g.SurroundGrids()), len(other.SurroundGrids()), msg)
+	}
+	require.Equal(t, a.Len(), b.Len(), msg)
+	for id, o := range a.objs {
+		x, y, _ := b.Pos(id)
+		require.Equal(t, []P{o.x, o.y}, []P{x, y}, msg)
+		ot, _ := b.Type(id)
+		require.Equal(t, o.ot, ot, msg)
+		require.ElementsMatch(t, a.VisibleTo(id), b.VisibleTo(id), msg)
+		require.ElementsMatch(t, a.VisibleBy(id), b.VisibleBy(id), msg)
+	}
+	require.Equal(t, a.Count(Trigger), b.Count(Trigger), msg)
+	require.Equal(t, a.ranged, b.ranged, msg)
+	require.Equal(t, a.masked, b.masked, msg)
+}
+
+func TestAOI_State(t *testing.T) {
+	const num = 200
+	tests := map[string][]Option{
+		"square":     {WithVisibility()},
+		"radius":     {WithRadius(2)},
+		"hex":        {WithHex(), WithVisibility()},
+		"sparse":     {WithSparse(), WithVisibility()},
+		"hysteresis": {WithHysteresis(3), WithVisibility()},
+		"wrap":       {WithWrap()},
+	}
+	for name, opts := range tests {
+		a, err := NewAOIManagerFrom[int, float64](-50, -30, 200, 160, 10, 8, opts...)
+		require.Nil(t, err)
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			var eo []EnterOption
+			if id%5 == 0 {
+				eo = append(eo, WithViewRadius(15))
+			}
+			if id%7 == 0 {
+				eo = append(eo, WithMask(1, 1))
+			}
+			a.Enter(id, rand.Float64()*200-50, rand.Float64()*160-30, ots[rand.Intn(len(ots))], nil, eo...)
+		}
+		for id := 0; id < num; id += 2 {
+			x, y, _ := a.Pos(id)
+			a.Move(id, x+rand.Float64()*6-3, y+rand.Float64()*6-3, nil)
+		}
+
+		data, err := a.State().MarshalBinary()
+		require.Nil(t, err)
+		var s State[int, float64]
+		require.Nil(t, s.UnmarshalBinary(data))
+		b, err := Restore(&s)
+		require.Nil(t, err, name)
+		requireSameState(t, a, b, name)
+
+		data, err = json.Marshal(a.State())
+		require.Nil(t, err)
+		s = State[int, float64]{}
+		require.Nil(t, json.Unmarshal(data, &s))
+		c, err := Restore(&s)
+		require.Nil(t, err, name)
+		requireSameState(t, a, c, name)
+
+		// 对象按格子和id排序, 恢复后的快照和原来完全一致
+		require.True(t, slices.IsSortedFunc(a.State().Objs, func(x, y ObjState[int, float64]) int {
+			if x.Grid != y.Grid {
+				return x.Grid - y.Grid
+			}
+			return x.ID - y.ID
+		}), name)
+		again, err := json.Marshal(c.State())
+		require.Nil(t, err)
+		require.Equal(t, data, again, name)
+
+		// 恢复后的事件和原来一致
+		for i := 0; i < 20; i++ {
+			id := rand.Intn(num)
+			x, y := rand.Float64()*200-50, rand.Float64()*160-30
+			r1, r2 := eventRecorder{}, eventRecorder{}
+			a.Move(id, x, y, r1.callFunc())
+			b.Move(id, x, y, r2.callFunc())
+			r1.requireEqual(t, r2, name)
+		}
+		requireSameState(t, a, b, name)
+	}
+}
+
+func TestAOI_State3D(t *testing.T) {
+	a, err := NewAOIManager3D[int, int](100, 100, 100, 10, 10, 10)
+	require.Nil(t, err)
+	for id := 0; id < 100; id++ {
+		a.Enter(id, rand.Intn(100), rand.Intn(100), rand.Intn(100), TriggerAndObserver, nil)
+	}
+	data, err := json.Marshal(a.State())
+	require.Nil(t, err)
+	var s State[int, int]
+	require.Nil(t, json.Unmarshal(data, &s))
+	b, err := Restore3D(&s)
+	require.Nil(t, err)
+	requireSameState(t, a.m, b.m, "3d")
+	for id := 0; id < 100; id++ {
+		x1, y1, z1, _ := a.Pos(id)
+		x2, y2, z2, _ := b.Pos(id)
+		require.Equal(t, []int{x1, y1, z1}, []int{x2, y2, z2})
+	}
+}
+
+func TestAOI_StateError(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	data, err := a.State().MarshalBinary()
+	require.Nil(t, err)
+
+	var s State[int, int]
+	require.NotNil(t, s.UnmarshalBinary(data[:3]))
+	require.NotNil(t, s.UnmarshalBinary(append([]byte("XXXX"), data[4:]...)))
+	bad := append([]byte{}, data...)
+	bad[5] = StateVersion + 1
+	require.NotNil(t, s.UnmarshalBinary(bad))
+
+	s = *a.State()
+	s.Version = StateVersion + 1
+	_, err = Restore(&s)
+	require.NotNil(t, err)
+
+	s = *a.State()
+	s.Objs = append(s.Objs, s.Objs[0])
+	_, err = Restore(&s)
+	require.NotNil(t, err)
+
+	s = *a.State()
+	s.Objs[0].Grid = 100
+	_, err = Restore(&s)
+	require.NotNil(t, err)
+
+	// 没有滞后时对象必须在坐标所在的格子
+	s = *a.State()
+	s.Objs[0].Grid = 0
+	_, err = Restore(&s)
+	require.NotNil(t, err)
+
+	s = *a.State()
+	s.Config.GridW = 0
+	_, err = Restore(&s)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,51 @@
+package aoi
+
+/*
+可见集合
+
+开启WithVisibility后, 管理器自己维护每个对象的可见集合, 规则和SetListener一致:
+观察者能看到相邻范围内(考虑观察者自己的可见半径)的触发者。
+不管Enter, Leave, Move和MoveMany有没有传回调, 可见集合都是最新的。
+*/
+
+// WithVisibility 维护可见集合, 可以用VisibleTo和VisibleBy查询
+func WithVisibility() Option {
+	return func(o *options) {
+		o.visibility = true
+	}
+}
+
+// VisibleTo id能看到的对象
+// 对象不存在或者没有开启WithVisibility时返回nil
+func (m *AOIManager[ObjID, P]) VisibleTo(id ObjID) []ObjID {
+	o, ok := m.objs[id]
+	if !ok || o.sees == nil {
+		return nil
+	}
+	return o.sees.slice()
+}
+
+// VisibleBy 能看到id的观察者
+// 对象不存在或者没有开启WithVisibility时返回nil
+func (m *AOIManager[ObjID, P]) VisibleBy(id ObjID) []ObjID {
+	o, ok := m.objs[id]
+	if !ok || o.seenBy == nil {
+		return nil
+	}
+	return o.seenBy.slice()
+}
+
+// updateVisible 根据observer视角的事件更新可见集合
+func (m *AOIManager[ObjID, P]) updateVisible(event EventType, observerID ObjID, observer *obj[ObjID, P], targetID ObjID, target *obj[ObjID, P]) {
+	if !m.visibility {
+		return
+	}
+	switch event {
+	case EnterView:
+		observer.sees[targetID] = struct{}{}
+		target.seenBy[observerID] = struct{}{}
+	case LeaveView:
+		delete(observer.sees, targetID)
+		delete(target.seenBy, observerID)
+	}
+}
@@ -0,0 +1,97 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_Visibility(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10)
+	require.Nil(t, err)
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	require.Nil(t, a.VisibleTo(1))
+
+	a, err = NewAOIManager[int](100, 100, 10, 10, WithVisibility())
+	require.Nil(t, err)
+	a.Enter(1, 15, 15, TriggerAndObserver, nil)
+	a.Enter(2, 25, 15, Trigger, nil)
+	a.Enter(3, 15, 25, Observer, nil)
+	require.Nil(t, a.VisibleTo(4))
+	require.ElementsMatch(t, []int{2}, a.VisibleTo(1))
+	require.ElementsMatch(t, []int{}, a.VisibleTo(2))
+	require.ElementsMatch(t, []int{1, 2}, a.VisibleTo(3))
+	require.ElementsMatch(t, []int{3}, a.VisibleBy(1))
+	require.ElementsMatch(t, []int{1, 3}, a.VisibleBy(2))
+	require.ElementsMatch(t, []int{}, a.VisibleBy(3))
+
+	a.Move(2, 85, 85, nil)
+	require.ElementsMatch(t, []int{}, a.VisibleTo(1))
+	require.ElementsMatch(t, []int{}, a.VisibleBy(2))
+
+	a.Leave(1, nil)
+	require.ElementsMatch(t, []int{}, a.VisibleTo(3))
+}
+
+func TestAOI_VisibilityRandom(t *testing.T) {
+	const (
+		w, h = 200, 200
+		num  = 200
+	)
+	for _, sparse := range []bool{false, true} {
+		opts := []Option{WithVisibility()}
+		if sparse {
+			opts = append(opts, WithSparse())
+		}
+		a, err := NewAOIManager[int](w, h, 10, 10, opts...)
+		require.Nil(t, err)
+
+		ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+		for id := 0; id < num; id++ {
+			a.Enter(id, rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))], nil, WithViewRadius(float64(rand.Intn(20))))
+		}
+		for i := 0; i < 1000; i++ {
+			id := rand.Intn(num)
+			switch i % 10 {
+			case 0:
+				ot := a.objs[id].ot
+				a.Leave(id, nil)
+				a.Enter(id, rand.Intn(w), rand.Intn(h), ot, nil)
+			case 1:
+				var ops []MoveOp[int, int]
+				for j := 0; j < 20; j++ {
+					ops = append(ops, MoveOp[int, int]{rand.Intn(num), rand.Intn(w), rand.Intn(h)})
+				}
+				a.MoveMany(ops, nil)
+			default:
+				a.Move(id, rand.Intn(w), rand.Intn(h), nil)
+			}
+		}
+
+		// 每个方向只看观察者自己的可见半径
+		inView := func(observer, target *obj[int, int]) bool {
+			dx, dy := float64(observer.x-target.x), float64(observer.y-target.y)
+			return observer.viewRadius <= 0 || dx*dx+dy*dy <= observer.viewRadius*observer.viewRadius
+		}
+		for id, o := range a.objs {
+			var sees, seenBy []int
+			a.ObjGrid(id).ForeachInSurroundGrids(func(other int) bool {
+				if other == id {
+					return true
+				}
+				t := a.objs[other]
+				if o.ot.IsObserver() && t.ot.IsTrigger() && inView(o, t) {
+					sees = append(sees, other)
+				}
+				if o.ot.IsTrigger() && t.ot.IsObserver() && inView(t, o) {
+					seenBy = append(seenBy, other)
+				}
+				return true
+			})
+			require.ElementsMatch(t, sees, a.VisibleTo(id), fmt.Sprint(id))
+			require.ElementsMatch(t, seenBy, a.VisibleBy(id), fmt.Sprint(id))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package aoi
+
+import "math"
+
+/*
+环形地图(torus)
+
+地图左右边界和上下边界首尾相连, 从maxX走出去会从minX回来。
+最边上的格子和对面最边上的格子相邻, 跨越边界移动和普通移动一样是UpdateView,
+不会变成LeaveView+EnterView。
+超出地图范围的坐标按地图宽高取模, 对象之间的距离也取环形地图上的最短距离。
+z轴不环绕。
+*/
+
+// WithWrap 环形地图, 边界的格子和对面的格子相邻
+// 不能和WithHex一起使用
+func WithWrap() Option {
+	return func(o *options) {
+		o.wrap = true
+	}
+}
+
+// wrapCoord 把坐标取模到[min, max)
+func wrapCoord[P Coord](pos, min, max P) P {
+	if pos >= min && pos < max {
+		return pos
+	}
+	size := float64(max - min)
+	d := math.Mod(float64(pos)-float64(min), size)
+	if d < 0 {
+		d += size
+	}
+	return min + P(d)
+}
+
+// wrapIndex 把下标取模到[0, n)
+func wrapIndex(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// indexDelta 两个格子下标的距离, 环形地图取较短的一边
+func (m *AOIManager[ObjID, P]) indexDelta(a, b, n int) int {
+	d := abs(a - b)
+	if m.wrap && n-d < d {
+		d = n - d
+	}
+	return d
+}
+
+// coordDelta 两个坐标的距离, 环形地图取较短的一边
+func (m *AOIManager[ObjID, P]) coordDelta(a, b, min, max P) float64 {
+	d := math.Abs(float64(a) - float64(b))
+	if m.wrap {
+		size := float64(max - min)
+		d = math.Mod(d, size)
+		if size-d < d {
+			d = size - d
+		}
+	}
+	return d
+}
+
+// posDistanceSq 两个坐标距离的平方, 环形地图取最短距离
+func (m *AOIManager[ObjID, P]) posDistanceSq(x1, y1, z1, x2, y2, z2 P) float64 {
+	dx := m.coordDelta(x1, x2, m.minX, m.maxX)
+	dy := m.coordDelta(y1, y2, m.minY, m.maxY)
+	dz := float64(z1) - float64(z2)
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,85 @@
+package aoi
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOI_WrapInit(t *testing.T) {
+	_, err := NewAOIManager[int](100, 100, 10, 10, WithWrap(), WithHex())
+	require.NotNil(t, err)
+
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithWrap())
+	require.Nil(t, err)
+	for _, g := range a.AllGrids() {
+		require.Len(t, g.SurroundGrids(), 9)
+	}
+	// 左上角和右下角相邻
+	require.True(t, a.PosAtGrid(0, 0).isSurround(a.PosAtGrid(99, 99).ID()))
+	// 出界的坐标取模
+	require.Same(t, a.PosAtGrid(5, 5), a.PosAtGrid(105, -95))
+	require.Same(t, a.PosAtGrid(95, 95), a.PosAtGrid(-5, -5))
+
+	// 地图比相邻范围还小时不会重复
+	a2, err := NewAOIManager[int](20, 20, 10, 10, WithWrap(), WithRadius(2))
+	require.Nil(t, err)
+	for _, g := range a2.AllGrids() {
+		require.Len(t, g.SurroundGrids(), 4)
+	}
+}
+
+func TestAOI_WrapMove(t *testing.T) {
+	a, err := NewAOIManager[int](100, 100, 10, 10, WithWrap())
+	require.Nil(t, err)
+
+	a.Enter(1, 2, 50, TriggerAndObserver, nil)
+	_shouldCall := testSet{1: {}}
+	a.Enter(2, 97, 50, TriggerAndObserver, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+
+	// 跨过边界是UpdateView
+	r := eventRecorder{}
+	a.Move(2, 101, 50, r.callFunc())
+	r.requireEqual(t, eventRecorder{UpdateView: {1}}, "cross")
+
+	a.Move(2, 80, 50, r.callFunc())
+	r.requireEqual(t, eventRecorder{UpdateView: {1}, LeaveView: {1}}, "leave")
+
+	// 可见半径按环形距离计算
+	a.Clear()
+	a.Enter(1, 1, 1, TriggerAndObserver, nil, WithViewRadius(5))
+	_shouldCall = testSet{1: {}}
+	a.Enter(2, 98, 98, Trigger, _shouldCall.callFunc(t))
+	_shouldCall.shouldEmpty(t)
+}
+
+func TestAOI_WrapSparse(t *testing.T) {
+	const (
+		w, h = 100, 100
+		num  = 100
+	)
+	dense, err := NewAOIManager[int](w, h, 10, 10, WithWrap())
+	require.Nil(t, err)
+	sparse, err := NewAOIManager[int](w, h, 10, 10, WithWrap(), WithSparse())
+	require.Nil(t, err)
+
+	ots := []ObjType{Trigger, Observer, TriggerAndObserver}
+	for id := 0; id < num; id++ {
+		x, y, ot := rand.Intn(w), rand.Intn(h), ots[rand.Intn(len(ots))]
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		dense.Enter(id, x, y, ot, r1.callFunc())
+		sparse.Enter(id, x, y, ot, r2.callFunc())
+		r1.requireEqual(t, r2, "enter")
+	}
+	for i := 0; i < 1000; i++ {
+		id := rand.Intn(num)
+		x, y := rand.Intn(2*w)-w/2, rand.Intn(2*h)-h/2
+		r1, r2 := eventRecorder{}, eventRecorder{}
+		dense.Move(id, x, y, r1.callFunc())
+		sparse.Move(id, x, y, r2.callFunc())
+		r1.requireEqual(t, r2, fmt.Sprint("move ", i))
+	}
+}